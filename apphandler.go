@@ -0,0 +1,190 @@
+package wurflgo
+
+import (
+	"regexp"
+	"strings"
+)
+
+// AppHandler claims the user agents of native apps, such as
+//
+//	AcmeApp/5.3 (iPhone14,2; iOS 17.1; Scale/3.00)
+//	AcmeApp/2.0 (Linux; Android 12; SM-G991B Build/SP1A.210812.016)
+//
+// which no browser handler recognizes. It reads the platform, model and
+// OS version the app reports (see Parse), writes them as the user agent
+// of the platform's browser and lets the handlers after it in the chain,
+// AndroidHandler and AppleHandler, find the device for that user agent
+// with their own stages: AndroidHandler looks the model up, and
+// AppleHandler picks the device for the iOS version in its recovery
+// match. Explain reports that handler and stage. Put it before them:
+//
+//	e.CustomizeChain(func(c *wurflgo.Chain) error {
+//		h := wurflgo.NewAppHandler("AcmeAppHandler", []string{"AcmeApp/"}, wurflgo.CreateGenericNormalizers())
+//		return c.InsertBefore("AndroidHandler", h)
+//	})
+//
+// The is_app virtual capability is true for the user agents it claims.
+type AppHandler struct {
+	BaseHandler
+	name   string
+	tokens []string
+}
+
+// AppInfo is what an AppHandler reads from an app's user agent. Fields
+// the user agent does not give are empty.
+type AppInfo struct {
+	// App is the app token without its trailing slash, e.g. AcmeApp,
+	// and AppVersion the version after it.
+	App        string
+	AppVersion string
+	// Platform is "iOS" or "Android".
+	Platform string
+	// Model is the hardware model, such as iPhone14,2, or the model of
+	// an Android device as AndroidHandler.GetAndroidModel reads it.
+	Model     string
+	OSVersion string
+}
+
+var (
+	appIOSRx     = regexp.MustCompile(`^(?:iOS|iPadOS|iPhone OS|CPU (?:iPhone )?OS)[ /](\d+(?:[._]\d+)*)`)
+	appAndroidRx = regexp.MustCompile(`^Android[ /](\d+(?:\.\d+)*)`)
+	appAppleRx   = regexp.MustCompile(`^(iPhone|iPad|iPod)(?:\d+,\d+| touch)?$`)
+	// appSkipRx matches the parts between the Android version and the
+	// model: locales and the U security token.
+	appSkipRx = regexp.MustCompile(`^(?:U|[a-z]{2}(?:[-_][a-zA-Z]{2})?)$`)
+)
+
+// NewAppHandler creates a handler called name that claims the user agents
+// containing any of tokens, e.g. "AcmeApp/".
+func NewAppHandler(name string, tokens []string, norm Normalizer) *AppHandler {
+	h := &AppHandler{name: name, tokens: tokens}
+	h.Init(h, norm)
+	return h
+}
+
+func (h *AppHandler) Name() string {
+	return h.name
+}
+
+// fingerprint tells snapshots apart that were written with other tokens
+// for the same name.
+func (h *AppHandler) fingerprint() string {
+	return strings.Join(h.tokens, "\x00")
+}
+
+func (h *AppHandler) CanHandle(ua string) bool {
+	return h.util.CheckIfContainsAnyOf(ua, h.tokens)
+}
+
+// Parse reads the app, platform, model and OS version from ua. They are
+// taken from the first parenthesis after the app token, or the first one
+// of ua when there is none after it, whose parts are separated by
+// semicolons.
+func (h *AppHandler) Parse(ua string) AppInfo {
+	info := AppInfo{}
+	start := -1
+	for _, token := range h.tokens {
+		if i := strings.Index(ua, token); i != -1 && (start == -1 || i < start) {
+			start = i
+			info.App = strings.TrimRight(token, "/ ")
+		}
+	}
+	if start == -1 {
+		return info
+	}
+	rest := ua[start+len(info.App):]
+	if strings.HasPrefix(rest, "/") {
+		info.AppVersion = rest[1:]
+		if end := strings.IndexAny(info.AppVersion, " ;("); end != -1 {
+			info.AppVersion = info.AppVersion[:end]
+		}
+	}
+	open := strings.Index(rest, "(")
+	if open == -1 {
+		// Web views put the app token after the browser's details.
+		rest = ua
+		if open = strings.Index(rest, "("); open == -1 {
+			return info
+		}
+	}
+	details := rest[open+1:]
+	if end := strings.Index(details, ")"); end != -1 {
+		details = details[:end]
+	}
+
+	parts := strings.Split(details, ";")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	for i, part := range parts {
+		if m := appIOSRx.FindStringSubmatch(part); m != nil {
+			info.Platform = "iOS"
+			info.OSVersion = strings.Replace(m[1], "_", ".", -1)
+		} else if m := appAndroidRx.FindStringSubmatch(part); m != nil {
+			info.Platform = "Android"
+			info.OSVersion = m[1]
+			// The model follows the version, after an optional locale.
+			for _, model := range parts[i+1:] {
+				if model == "" || appSkipRx.MatchString(model) {
+					continue
+				}
+				if b := strings.Index(model, " Build/"); b != -1 {
+					model = model[:b]
+				}
+				if info.Model == "" {
+					info.Model = cleanAndroidModel(model)
+				}
+				break
+			}
+		} else if appAppleRx.MatchString(part) {
+			info.Model = part
+			if info.Platform == "" {
+				info.Platform = "iOS"
+			}
+		}
+	}
+	return info
+}
+
+// BrowserUA returns the user agent of the platform's browser on the
+// device described by info, or "" when the platform is not known. For
+// iOS it ends with the hardware model, which some app web views send as
+// well, e.g. FBDV/iPhone14,2, so tables holding such user agents can
+// tell the models apart.
+func (info AppInfo) BrowserUA() string {
+	switch info.Platform {
+	case "iOS":
+		version := strings.Replace(info.OSVersion, ".", "_", -1)
+		if version == "" {
+			version = "1_0"
+		}
+		model := ""
+		if info.Model != "" {
+			model = " " + info.Model
+		}
+		switch {
+		case strings.HasPrefix(info.Model, "iPad"):
+			return "Mozilla/5.0 (iPad; CPU OS " + version + " like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148" + model
+		case strings.HasPrefix(info.Model, "iPod"):
+			return "Mozilla/5.0 (iPod touch; CPU iPhone OS " + version + " like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148" + model
+		}
+		return "Mozilla/5.0 (iPhone; CPU iPhone OS " + version + " like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148" + model
+	case "Android":
+		model := info.Model
+		if model == "" {
+			model = "Android"
+		}
+		return "Mozilla/5.0 (Linux; U; Android " + info.OSVersion + "; xx-xx; " + model + " Build/" + info.App + ") AppleWebKit/534.30 (KHTML, like Gecko) Version/4.0 Mobile Safari/534.30"
+	}
+	return ""
+}
+
+// ApplyConclusiveMatch matches the browser user agent of the app's
+// platform and device with the handlers after this one.
+func (h *AppHandler) ApplyConclusiveMatch(ua string) string {
+	browserUA := h.Parse(ua).BrowserUA()
+	if browserUA == "" || h.nextHandler == nil {
+		return NO_MATCH
+	}
+	return h.nextHandler.Match(browserUA)
+}
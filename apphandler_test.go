@@ -0,0 +1,82 @@
+package wurflgo
+
+import (
+	"strings"
+	"testing"
+)
+
+func testAppHandler() *AppHandler {
+	h := NewAppHandler("AcmeAppHandler", []string{"AcmeApp/"}, CreateGenericNormalizers())
+	h.SetUtil(NewUtil())
+	return h
+}
+
+func TestAppHandlerParse(t *testing.T) {
+	h := testAppHandler()
+	android := &AndroidHandler{}
+	for _, test := range []struct {
+		ua   string
+		want AppInfo
+	}{
+		{"AcmeApp/5.3 (iPhone14,2; iOS 17.1; Scale/3.00)", AppInfo{"AcmeApp", "5.3", "iOS", "iPhone14,2", "17.1"}},
+		{"AcmeApp/5.3 (iPad13,4; iPadOS 16_6_1; Scale/2.00)", AppInfo{"AcmeApp", "5.3", "iOS", "iPad13,4", "16.6.1"}},
+		{"AcmeApp/1.0 (iPod touch; iOS 12.5.7)", AppInfo{"AcmeApp", "1.0", "iOS", "iPod touch", "12.5.7"}},
+		{"AcmeApp/2.0 (Linux; Android 12; SM-G991B Build/SP1A.210812.016)", AppInfo{"AcmeApp", "2.0", "Android", "SM-G991B", "12"}},
+		{"AcmeApp/2.0 (Linux; U; Android 4.0.4; en-gb; GT-I9300 Build/IMM76D)", AppInfo{"AcmeApp", "2.0", "Android", "GT-I9300", "4.0.4"}},
+		// The model is cleaned as AndroidHandler cleans it.
+		{"AcmeApp/2.0 (Linux; Android 4.1.2; HTC_One_X Build/JZO54K)", AppInfo{"AcmeApp", "2.0", "Android", "HTC~One_X", "4.1.2"}},
+		// Web views put the app token after the browser's details.
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/21B80 AcmeApp/5.3", AppInfo{"AcmeApp", "5.3", "iOS", "iPhone", "17.1"}},
+		{"AcmeApp/5.3", AppInfo{App: "AcmeApp", AppVersion: "5.3"}},
+		{"AcmeApp/5.3 (Windows NT 10.0; Win64)", AppInfo{App: "AcmeApp", AppVersion: "5.3"}},
+		{"OtherApp/1.0 (iPhone14,2; iOS 17.1)", AppInfo{}},
+	} {
+		info := h.Parse(test.ua)
+		if info != test.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", test.ua, info, test.want)
+		}
+		browserUA := info.BrowserUA()
+		switch info.Platform {
+		case "Android":
+			if got := android.GetAndroidModel(browserUA); got != info.Model {
+				t.Errorf("%q: GetAndroidModel(%q) = %q, want %q", test.ua, browserUA, got, info.Model)
+			}
+		case "iOS":
+			if !strings.HasSuffix(browserUA, " "+info.Model) {
+				t.Errorf("%q: %q does not end with the model", test.ua, browserUA)
+			}
+		default:
+			if browserUA != "" {
+				t.Errorf("%q: got browser user agent %q for no platform", test.ua, browserUA)
+			}
+		}
+	}
+}
+
+// TestAppHandlerExplain checks that Explain reports the handler and stage
+// that matched the browser user agent an AppHandler hands on.
+func TestAppHandlerExplain(t *testing.T) {
+	e := newTestEngine(t, nil)
+	if err := e.CustomizeChain(func(c *Chain) error {
+		return c.InsertBefore("AndroidHandler", testAppHandler())
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		ua, handler, stage, id string
+	}{
+		{"AcmeApp/5.3 (iPhone14,2; iOS 17.1; Scale/3.00)", "AppleHandler", StageRecovery, "apple_iphone_ver4"},
+		{"AcmeApp/2.0 (Linux; Android 4.0.4; GT-I9300 Build/IMM76D)", "AndroidHandler", StageConclusive, "samsung_gt_i9300_ver1"},
+	} {
+		res := e.Explain(test.ua)
+		if res.Handler != test.handler || res.Via != "AcmeAppHandler" || res.Stage != test.stage || res.DeviceId != test.id {
+			t.Errorf("%q: got %s by %s/%s via %q, want %s by %s/%s via AcmeAppHandler", test.ua, res.DeviceId, res.Handler, res.Stage, res.Via, test.id, test.handler, test.stage)
+		}
+		if res.UserAgent != test.ua {
+			t.Errorf("%q: got user agent %q", test.ua, res.UserAgent)
+		}
+		if id := e.Match(test.ua).Id; id != res.DeviceId {
+			t.Errorf("%q: Match gives %s, Explain %s", test.ua, id, res.DeviceId)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package wurflgo
+
+import (
+	"sort"
+	"strings"
+)
+
+// BaseHandler owns the UA table of a handler and runs the match pipeline:
+// exact, conclusive, recovery and recovery catch-all matches, in that
+// order, until one of them finds a device. A handler embeds it and
+// supplies CanHandle; it may also supply Tolerance, ApplyConclusiveMatch
+// and ApplyRecoveryMatch to change how the stages look for a device.
+//
+//	type AcmeHandler struct {
+//		wurflgo.BaseHandler
+//	}
+//
+//	func NewAcmeHandler(norm wurflgo.Normalizer) *AcmeHandler {
+//		h := new(AcmeHandler)
+//		h.Init(h, norm)
+//		return h
+//	}
+//
+//	func (h *AcmeHandler) CanHandle(ua string) bool {
+//		return strings.HasPrefix(ua, "Acme")
+//	}
+type BaseHandler struct {
+	OrderedUAS      []string
+	Normalizer      Normalizer
+	UASWithDeviceId map[string]string
+
+	nextHandler Handlers
+	util        *Util
+	// self is the handler embedding BaseHandler. The pipeline calls the
+	// stages through it, so the handler's own stages are used.
+	self Handlers
+}
+
+// ToleranceHandler is implemented by handlers that only change the
+// tolerance of the RIS lookup of the conclusive match. The first slash of
+// the user agent is used otherwise.
+type ToleranceHandler interface {
+	Tolerance(ua string) int
+}
+
+// Init prepares the embedded BaseHandler of self. The handler gets its
+// Util from the chain it is added to, see SetUtil.
+func (b *BaseHandler) Init(self Handlers, norm Normalizer) {
+	b.self = self
+	b.Normalizer = norm
+	b.OrderedUAS = []string{}
+	b.UASWithDeviceId = make(map[string]string)
+}
+
+func (b *BaseHandler) base() *BaseHandler {
+	return b
+}
+
+// Util returns the keyword lists the handler matches with.
+func (b *BaseHandler) Util() *Util {
+	return b.util
+}
+
+// SetUtil makes the handler, and its normalizers that use one, match
+// with u.
+func (b *BaseHandler) SetUtil(u *Util) {
+	b.util = u
+	if n, ok := b.Normalizer.(utilNormalizer); ok {
+		n.SetUtil(u)
+	}
+}
+
+func (b *BaseHandler) SetNextHandler(h Handlers) {
+	b.nextHandler = h
+}
+
+// Filter files ua with the first handler of the chain that can handle it.
+func (b *BaseHandler) Filter(ua string, deviceId string) {
+	if b.self.CanHandle(ua) {
+		b.UASWithDeviceId[b.Normalizer.Normalize(ua)] = deviceId
+		b.OrderedUAS = []string{}
+		return
+	}
+	if b.nextHandler != nil {
+		b.nextHandler.Filter(ua, deviceId)
+	}
+}
+
+// Match returns the device id for ua from the first handler of the chain
+// that can handle it.
+func (b *BaseHandler) Match(ua string) string {
+	if b.self.CanHandle(ua) {
+		return b.self.ApplyMatch(ua)
+	}
+	if b.nextHandler != nil {
+		return b.nextHandler.Match(ua)
+	}
+	return GENERIC
+}
+
+// ApplyMatch runs the stages on the normalized ua until one finds a
+// device. It returns GENERIC when none does. Under Explain it records
+// the stages it runs, see matchTrace.
+func (b *BaseHandler) ApplyMatch(ua string) string {
+	ua = b.Normalizer.Normalize(ua)
+	var trace *matchTrace
+	if b.util != nil {
+		trace = b.util.trace
+	}
+	step := trace.begin(b.self, ua)
+	stages := []struct {
+		name  string
+		apply func(string) string
+	}{
+		{StageExact, b.self.ApplyExactMatch},
+		{StageConclusive, b.self.ApplyConclusiveMatch},
+		{StageRecovery, b.self.ApplyRecoveryMatch},
+		{StageRecoveryCatchAll, b.self.ApplyRecoveryCatchAllMatch},
+	}
+	for _, stage := range stages {
+		step.enter(stage.name)
+		if deviceId := stage.apply(ua); !b.self.IsBlankOrGeneric(deviceId) {
+			trace.end(step, deviceId)
+			return deviceId
+		}
+	}
+	trace.end(step, GENERIC)
+	return GENERIC
+}
+
+func (b *BaseHandler) ApplyExactMatch(ua string) string {
+	if deviceId, found := b.UASWithDeviceId[ua]; found {
+		return deviceId
+	}
+	return NO_MATCH
+}
+
+// ApplyConclusiveMatch looks ua up in the handler's table with RIS, see
+// LookForMatchingUA.
+func (b *BaseHandler) ApplyConclusiveMatch(ua string) string {
+	match := b.self.LookForMatchingUA(ua)
+	if len(match) > 0 {
+		return b.UASWithDeviceId[match]
+	}
+	return NO_MATCH
+}
+
+// LookForMatchingUA returns the UA of the handler's table sharing the
+// longest prefix with ua, at least as long as the handler's tolerance.
+func (b *BaseHandler) LookForMatchingUA(ua string) string {
+	tolerance := b.util.FirstSlash(ua)
+	if t, ok := b.self.(ToleranceHandler); ok {
+		tolerance = t.Tolerance(ua)
+	}
+	return b.util.RISMatch(b.self.GetOrderedUAS(), ua, tolerance)
+}
+
+func (b *BaseHandler) ApplyRecoveryMatch(ua string) string {
+	return NO_MATCH
+}
+
+// ApplyRecoveryCatchAllMatch picks a generic device from the keywords of
+// ua.
+func (b *BaseHandler) ApplyRecoveryCatchAllMatch(ua string) string {
+	if b.util.IsDesktopBrowserHeavyDutyAnalysis(ua) {
+		return GENERIC_WEB_BROWSER
+	}
+	mobile := b.util.IsMobileBrowser(ua)
+	desktop := b.util.IsDesktopBrowser(ua)
+	if !desktop {
+		deviceId := b.util.GetMobileCatchAllId(ua)
+		if deviceId != NO_MATCH {
+			return deviceId
+		}
+	}
+	if mobile {
+		return GENERIC_MOBILE
+	}
+	if desktop {
+		return GENERIC_WEB_BROWSER
+	}
+	return GENERIC
+}
+
+func (b *BaseHandler) GetDeviceIdFromRIS(ua string, tolerance int) string {
+	match := b.util.RISMatch(b.self.GetOrderedUAS(), ua, tolerance)
+	if match != "" {
+		return b.UASWithDeviceId[match]
+	}
+	return NO_MATCH
+}
+
+func (b *BaseHandler) GetDeviceIdFromLD(ua string, tolerance int) string {
+	match := b.util.LDMatch(b.self.GetOrderedUAS(), ua, tolerance)
+	if match != "" {
+		return b.UASWithDeviceId[match]
+	}
+	return NO_MATCH
+}
+
+func (b *BaseHandler) IsBlankOrGeneric(deviceId string) bool {
+	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId, " ")) == 0
+}
+
+// GetOrderedUAS returns the UAs of the handler's table, sorted on the
+// first call after a Filter.
+func (b *BaseHandler) GetOrderedUAS() []string {
+	if len(b.OrderedUAS) == 0 {
+		for k := range b.UASWithDeviceId {
+			b.OrderedUAS = append(b.OrderedUAS, k)
+		}
+		sort.Strings(b.OrderedUAS)
+	}
+	return b.OrderedUAS
+}
+
+func (b *BaseHandler) GetUASWithDeviceId() map[string]string {
+	return b.UASWithDeviceId
+}
+
+func (b *BaseHandler) GetNormalizer() Normalizer {
+	return b.Normalizer
+}
+
+func (b *BaseHandler) SetOrderedUAS(uas []string) {
+	b.OrderedUAS = uas
+}
@@ -0,0 +1,174 @@
+package wurflgo
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/srinathgs/wurflgo/matcher"
+)
+
+// benchUAs returns n distinct user agents shaped like the Mozilla/5 user
+// agents of wurfl.xml, which holds tens of thousands of them.
+func benchUAs(n int) []string {
+	uas := make([]string, n)
+	for i := range uas {
+		switch i % 3 {
+		case 0:
+			uas[i] = fmt.Sprintf("Mozilla/5.0 (Linux; U; Android %d.%d; en-us; SM-G%04d Build/IMM76D) AppleWebKit/534.30 (KHTML, like Gecko) Version/4.0 Mobile Safari/534.30", 2+i%3, i%10, i)
+		case 1:
+			uas[i] = fmt.Sprintf("Mozilla/5.0 (iPhone; CPU iPhone OS %d_%d like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/%dE%d", 4+i%14, i%4, 10+i%10, i)
+		default:
+			uas[i] = fmt.Sprintf("Mozilla/5.0 (Windows NT 6.1; rv:%d.0) Gecko/20100101 Firefox/%d.%d", 2+i%40, 2+i%40, i)
+		}
+	}
+	return uas
+}
+
+func benchTable(uas []string) map[string]string {
+	table := make(map[string]string, len(uas))
+	for i, ua := range uas {
+		table[ua] = fmt.Sprintf("device_%d", i)
+	}
+	return table
+}
+
+// BenchmarkExactMatch compares the map lookup of
+// BaseHandler.ApplyExactMatch with the scan of the table it replaced.
+func BenchmarkExactMatch(b *testing.B) {
+	uas := benchUAs(20000)
+	h := NewAlcatelHandler(CreateGenericNormalizers())
+	h.SetUtil(NewUtil())
+	h.UASWithDeviceId = benchTable(uas)
+	b.Run("map", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			h.ApplyExactMatch(uas[i%len(uas)])
+		}
+	})
+	b.Run("loop", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ua := uas[i%len(uas)]
+			for k, v := range h.UASWithDeviceId {
+				if k == ua {
+					_ = v
+					break
+				}
+			}
+		}
+	})
+}
+
+// BenchmarkMozillaKeys compares the Aho-Corasick index CatchAllHandler
+// uses to tell whether a user agent contains a known Mozilla/5 UA with
+// the per request key list and scan it replaced.
+func BenchmarkMozillaKeys(b *testing.B) {
+	uas := benchUAs(20000)
+	h := NewCatchAllHandler(CreateGenericNormalizers())
+	h.SetUtil(NewUtil())
+	h.Mozilla5UASWithDeviceId = benchTable(uas)
+	h.freeze()
+	// A user agent that contains none of them, which scans the most.
+	ua := "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	b.Run("ahocorasick", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			h.getMozilla5Keys().ContainsAny(ua)
+		}
+	})
+	b.Run("loop", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			keys := []string{}
+			for k := range h.Mozilla5UASWithDeviceId {
+				keys = append(keys, k)
+			}
+			h.util.CheckIfContainsAnyOf(ua, keys)
+		}
+	})
+}
+
+// BenchmarkMozillaKeysIndex builds the index of BenchmarkMozillaKeys and
+// reports the heap it keeps as index-bytes.
+func BenchmarkMozillaKeysIndex(b *testing.B) {
+	uas := benchUAs(20000)
+	var before, after runtime.MemStats
+	var index *matcher.AhoCorasick
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		index = nil
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		index = matcher.NewAhoCorasick(uas)
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+	}
+	b.ReportMetric(float64(after.HeapAlloc)-float64(before.HeapAlloc), "index-bytes")
+	runtime.KeepAlive(index)
+}
+
+// BenchmarkIsMobileBrowser compares the keyword scanner of
+// Util.IsMobileBrowser with the scan of the keyword list it replaced.
+func BenchmarkIsMobileBrowser(b *testing.B) {
+	u := NewUtil()
+	b.Run("ahocorasick", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			u.IsMobileBrowser(testUAs[i%len(testUAs)])
+		}
+	})
+	b.Run("loop", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			u.CheckIfContainsAnyOf(strings.ToLower(testUAs[i%len(testUAs)]), u.MobileBrowsers)
+		}
+	})
+}
+
+func BenchmarkIsDesktopBrowser(b *testing.B) {
+	u := NewUtil()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		u.IsDesktopBrowser(testUAs[i%len(testUAs)])
+	}
+}
+
+func BenchmarkIsDesktopBrowserHeavyDutyAnalysis(b *testing.B) {
+	u := NewUtil()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		u.IsDesktopBrowserHeavyDutyAnalysis(testUAs[i%len(testUAs)])
+	}
+}
+
+// BenchmarkRemoveLocale compares the precompiled regexp of
+// Util.RemoveLocale with compiling it on every call, as it used to.
+func BenchmarkRemoveLocale(b *testing.B) {
+	u := NewUtil()
+	b.Run("precompiled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			u.RemoveLocale(testUAs[i%len(testUAs)])
+		}
+	})
+	b.Run("compile", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			regexp.MustCompile(localeRx.String()).ReplaceAllString(testUAs[i%len(testUAs)], `; xx-xx`)
+		}
+	})
+}
+
+// BenchmarkMatch reports the allocations of a whole match, without the
+// cache.
+func BenchmarkMatch(b *testing.B) {
+	e := newTestEngine(b, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e.Match(testUAs[i%len(testUAs)])
+	}
+}
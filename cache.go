@@ -0,0 +1,121 @@
+package wurflgo
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CacheStats reports the activity of an engine's match cache, see
+// EngineOptions.CacheSize. The counters are not reset by Reload.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// Len is the number of cached results and Size the most the cache
+	// holds.
+	Len  int
+	Size int
+}
+
+// matchCache is a bounded LRU cache of match results. Every entry
+// remembers the dataset and the generation of the dataset it was
+// computed from, so results never outlive a Reload or a RegisterDevice.
+// A nil *matchCache caches nothing.
+type matchCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+
+	hits, misses, evictions uint64
+}
+
+type cacheEntry struct {
+	key string
+	ds  *dataset
+	gen uint64
+	dev *Device
+	err error
+}
+
+func newMatchCache(size int) *matchCache {
+	if size <= 0 {
+		return nil
+	}
+	return &matchCache{size: size, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get returns the result cached for key, if it was computed from the
+// current state of ds. The caller holds ds.mu.
+func (c *matchCache) get(ds *dataset, key string) (*Device, error, bool) {
+	if c == nil {
+		return nil, nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, found := c.items[key]; found {
+		entry := el.Value.(*cacheEntry)
+		if entry.ds == ds && entry.gen == ds.gen {
+			c.ll.MoveToFront(el)
+			c.hits++
+			return entry.dev, entry.err, true
+		}
+	}
+	c.misses++
+	return nil, nil, false
+}
+
+// add caches the result for key, evicting the least recently used
+// result when the cache is full. The caller holds ds.mu.
+func (c *matchCache) add(ds *dataset, key string, dev *Device, err error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, found := c.items[key]; found {
+		entry := el.Value.(*cacheEntry)
+		entry.ds, entry.gen, entry.dev, entry.err = ds, ds.gen, dev, err
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, ds: ds, gen: ds.gen, dev: dev, err: err})
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+		c.evictions++
+	}
+}
+
+// purge drops every cached result. Stale results are never returned, so
+// this only gives their memory back.
+func (c *matchCache) purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+func (c *matchCache) stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Evictions: c.evictions, Len: c.ll.Len(), Size: c.size}
+}
+
+// CacheStats returns the counters of the engine's match cache. They are
+// all zero when the engine has no cache.
+func (e *Engine) CacheStats() CacheStats {
+	return e.cache.stats()
+}
+
+// PurgeCache empties the engine's match cache.
+func (e *Engine) PurgeCache() {
+	e.cache.purge()
+}
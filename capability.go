@@ -0,0 +1,346 @@
+package wurflgo
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CapabilityType is the Go type a capability value is converted to when
+// a device is registered.
+type CapabilityType int
+
+const (
+	CapabilityString CapabilityType = iota
+	CapabilityBool
+	CapabilityInt
+	CapabilityFloat
+)
+
+func (t CapabilityType) String() string {
+	switch t {
+	case CapabilityBool:
+		return "bool"
+	case CapabilityInt:
+		return "int"
+	case CapabilityFloat:
+		return "float"
+	}
+	return "string"
+}
+
+// CapabilitySchema fixes the type of some capabilities by name. The type
+// of the capabilities it does not list is inferred from their values, see
+// InferCapabilitySchema.
+type CapabilitySchema map[string]CapabilityType
+
+// DefaultCapabilitySchema lists the capabilities of wurfl.xml that are
+// strings even though some devices give them a numeric value, such as
+// model_name "6300" or device_os_version "4.0".
+var DefaultCapabilitySchema = CapabilitySchema{
+	"brand_name":                   CapabilityString,
+	"model_name":                   CapabilityString,
+	"marketing_name":               CapabilityString,
+	"model_extra_info":             CapabilityString,
+	"release_date":                 CapabilityString,
+	"device_os":                    CapabilityString,
+	"device_os_version":            CapabilityString,
+	"mobile_browser":               CapabilityString,
+	"mobile_browser_version":       CapabilityString,
+	"advertised_device_os":         CapabilityString,
+	"advertised_device_os_version": CapabilityString,
+	"advertised_browser":           CapabilityString,
+	"advertised_browser_version":   CapabilityString,
+	"nokia_series":                 CapabilityString,
+	"nokia_edition":                CapabilityString,
+	"nokia_feature_pack":           CapabilityString,
+	"uaprof":                       CapabilityString,
+	"uaprof2":                      CapabilityString,
+	"uaprof3":                      CapabilityString,
+}
+
+var ErrUnknownCapability = errors.New("Unknown capability")
+
+// CapabilityTypeError is returned by the typed accessors of Device when
+// the capability holds a value of another type.
+type CapabilityTypeError struct {
+	Name  string
+	Want  CapabilityType
+	Value interface{}
+}
+
+func (e *CapabilityTypeError) Error() string {
+	return fmt.Sprintf("Capability %s is %v (%T), not %s", e.Name, e.Value, e.Value, e.Want)
+}
+
+// ConvertCapability converts the raw wurfl.xml value of a capability. A
+// capability listed in schema gets the type given there; a value that
+// cannot be parsed as that type, such as "" for an int, becomes the zero
+// value of the type. The type of other capabilities is inferred from the
+// value alone, see valueType, so use a schema from InferCapabilitySchema
+// to give a capability the same type on every device.
+func ConvertCapability(name, value string, schema CapabilitySchema) interface{} {
+	t, found := schema[name]
+	if !found {
+		t = valueType(value)
+	}
+	if v, ok := parseCapability(value, t); ok {
+		return v
+	}
+	return zeroCapability(t)
+}
+
+// InferCapabilitySchema returns schema completed with a type for every
+// other capability the devices set. A capability is a bool when all its
+// non-empty values are "true" or "false", an int when they are all
+// decimal integers, a float when they are all decimal numbers and a
+// string otherwise.
+func InferCapabilitySchema(devices []*DeviceDefinition, schema CapabilitySchema) CapabilitySchema {
+	inferred := make(CapabilitySchema, len(schema))
+	for name, t := range schema {
+		inferred[name] = t
+	}
+	seen := map[string]bool{}
+	for _, dev := range devices {
+		for _, grp := range dev.Groups {
+			for _, c := range grp.Capabilities {
+				if _, fixed := schema[c.Name]; fixed || c.Value == "" {
+					continue
+				}
+				t := valueType(c.Value)
+				if seen[c.Name] {
+					t = widerType(inferred[c.Name], t)
+				}
+				seen[c.Name] = true
+				inferred[c.Name] = t
+			}
+		}
+	}
+	for _, dev := range devices {
+		for _, grp := range dev.Groups {
+			for _, c := range grp.Capabilities {
+				if _, found := inferred[c.Name]; !found {
+					inferred[c.Name] = CapabilityString
+				}
+			}
+		}
+	}
+	return inferred
+}
+
+// valueType is the type a single value looks like: bool for "true" and
+// "false", int or float for decimal numbers and string otherwise.
+func valueType(value string) CapabilityType {
+	switch {
+	case value == "true" || value == "false":
+		return CapabilityBool
+	case !isDecimal(value):
+		return CapabilityString
+	}
+	if _, ok := parseCapability(value, CapabilityInt); ok {
+		return CapabilityInt
+	}
+	if _, ok := parseCapability(value, CapabilityFloat); ok {
+		return CapabilityFloat
+	}
+	return CapabilityString
+}
+
+// widerType is the type that holds values of both a and b.
+func widerType(a, b CapabilityType) CapabilityType {
+	switch {
+	case a == b:
+		return a
+	case (a == CapabilityInt && b == CapabilityFloat) || (a == CapabilityFloat && b == CapabilityInt):
+		return CapabilityFloat
+	}
+	return CapabilityString
+}
+
+func zeroCapability(t CapabilityType) interface{} {
+	switch t {
+	case CapabilityBool:
+		return false
+	case CapabilityInt:
+		return 0
+	case CapabilityFloat:
+		return 0.0
+	}
+	return ""
+}
+
+// typeOf returns the type of a converted capability value.
+func typeOf(value interface{}) CapabilityType {
+	switch value.(type) {
+	case bool:
+		return CapabilityBool
+	case int:
+		return CapabilityInt
+	case float64:
+		return CapabilityFloat
+	}
+	return CapabilityString
+}
+
+func parseCapability(value string, t CapabilityType) (interface{}, bool) {
+	switch t {
+	case CapabilityBool:
+		b, err := strconv.ParseBool(value)
+		return b, err == nil
+	case CapabilityInt:
+		i, err := strconv.Atoi(value)
+		return i, err == nil
+	case CapabilityFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	}
+	return value, true
+}
+
+// isDecimal reports whether value is written like a plain decimal number,
+// which keeps values such as "NaN", "1e3" or "0x10" strings.
+func isDecimal(value string) bool {
+	digits := strings.TrimPrefix(value, "-")
+	if digits == "" || digits[0] == '.' || digits[len(digits)-1] == '.' {
+		return false
+	}
+	dots := 0
+	for i := 0; i < len(digits); i++ {
+		switch {
+		case digits[i] == '.':
+			dots++
+		case digits[i] < '0' || digits[i] > '9':
+			return false
+		}
+	}
+	return dots <= 1
+}
+
+// typedCapabilities returns a copy of capabilities with every string value
+// converted according to schema or, for the capabilities schema does not
+// list, to the type the repository already holds them as. Values of other
+// types are kept as they are, so capabilities that are already typed pass
+// through unchanged.
+func (r *Repository) typedCapabilities(capabilities map[string]interface{}, schema CapabilitySchema) map[string]interface{} {
+	typed := make(map[string]interface{}, len(capabilities))
+	for name, value := range capabilities {
+		s, ok := value.(string)
+		if !ok {
+			typed[name] = value
+			continue
+		}
+		if _, found := schema[name]; !found {
+			if t, found := r.types[name]; found {
+				typed[name] = ConvertCapability(name, s, CapabilitySchema{name: t})
+				continue
+			}
+		}
+		typed[name] = ConvertCapability(name, s, schema)
+	}
+	return typed
+}
+
+// Source returns the device the value of a capability comes from: dev
+// itself when it sets the capability, otherwise the closest device up its
+// fall_back chain that does. It returns nil for unknown capabilities.
+func (dev *Device) Source(name string) *Device {
+	for d := dev; d != nil; d = d.Parent {
+		if _, found := d.Capabilities[name]; found {
+			return d
+		}
+	}
+	return nil
+}
+
+func (dev *Device) lookup(name string) (interface{}, bool) {
+	if dev.flat != nil {
+		value, found := dev.flat[name]
+		return value, found
+	}
+	if src := dev.Source(name); src != nil {
+		return src.Capabilities[name], true
+	}
+	return nil, false
+}
+
+// AllCapabilities returns a new map with the own and inherited
+// capabilities of the device.
+func (dev *Device) AllCapabilities() map[string]interface{} {
+	all := make(map[string]interface{})
+	if dev.flat != nil {
+		for k, v := range dev.flat {
+			all[k] = v
+		}
+		return all
+	}
+	for d := dev; d != nil; d = d.Parent {
+		for k, v := range d.Capabilities {
+			if _, found := all[k]; !found {
+				all[k] = v
+			}
+		}
+	}
+	return all
+}
+
+// Value returns the value of a capability, whatever its type, looking it
+// up through the fall_back chain.
+func (dev *Device) Value(name string) (interface{}, error) {
+	value, found := dev.lookup(name)
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCapability, name)
+	}
+	return value, nil
+}
+
+func (dev *Device) Bool(name string) (bool, error) {
+	value, err := dev.Value(name)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, &CapabilityTypeError{name, CapabilityBool, value}
+	}
+	return b, nil
+}
+
+func (dev *Device) Int(name string) (int, error) {
+	value, err := dev.Value(name)
+	if err != nil {
+		return 0, err
+	}
+	i, ok := value.(int)
+	if !ok {
+		return 0, &CapabilityTypeError{name, CapabilityInt, value}
+	}
+	return i, nil
+}
+
+// Float returns a float or int capability as a float64.
+func (dev *Device) Float(name string) (float64, error) {
+	value, err := dev.Value(name)
+	if err != nil {
+		return 0, err
+	}
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	}
+	return 0, &CapabilityTypeError{name, CapabilityFloat, value}
+}
+
+func (dev *Device) String(name string) (string, error) {
+	value, err := dev.Value(name)
+	if err != nil {
+		return "", err
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", &CapabilityTypeError{name, CapabilityString, value}
+	}
+	return s, nil
+}
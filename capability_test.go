@@ -0,0 +1,177 @@
+package wurflgo
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvertCapability(t *testing.T) {
+	schema := CapabilitySchema{"model_name": CapabilityString, "resolution_width": CapabilityInt}
+	for _, test := range []struct {
+		name, value string
+		want        interface{}
+	}{
+		{"model_name", "6300", "6300"},
+		{"resolution_width", "320", 320},
+		{"resolution_width", "", 0},
+		{"resolution_width", "wide", 0},
+		{"is_wireless_device", "true", true},
+		{"max_image_width", "228", 228},
+		{"density", "1.5", 1.5},
+		{"brand_name", "Nokia", "Nokia"},
+		{"brand_name", "", ""},
+	} {
+		got := ConvertCapability(test.name, test.value, schema)
+		if got != test.want {
+			t.Errorf("ConvertCapability(%s, %q) = %#v, want %#v", test.name, test.value, got, test.want)
+		}
+	}
+}
+
+func TestInferCapabilitySchema(t *testing.T) {
+	device := func(values map[string]string) *DeviceDefinition {
+		grp := CapabilityGroup{Id: "test"}
+		for name, value := range values {
+			grp.Capabilities = append(grp.Capabilities, Capability{name, value})
+		}
+		return &DeviceDefinition{Groups: []CapabilityGroup{grp}}
+	}
+	devices := []*DeviceDefinition{
+		device(map[string]string{"width": "", "mobile": "true", "density": "1", "model_name": "6300", "version": "1.0", "empty": ""}),
+		device(map[string]string{"width": "320", "mobile": "false", "density": "1.5", "model_name": "N95", "version": "2"}),
+		device(map[string]string{"version": "2.1.3"}),
+	}
+	want := CapabilitySchema{
+		"width":      CapabilityInt,
+		"mobile":     CapabilityBool,
+		"density":    CapabilityFloat,
+		"model_name": CapabilityString,
+		"version":    CapabilityString,
+		"empty":      CapabilityString,
+		"fixed":      CapabilityInt,
+	}
+	got := InferCapabilitySchema(devices, CapabilitySchema{"fixed": CapabilityInt, "mobile": CapabilityBool})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// TestLoadXMLCapabilityTypes checks that a capability gets the same type
+// on every device, whatever the order of the devices.
+func TestLoadXMLCapabilityTypes(t *testing.T) {
+	xml := `<wurfl><devices>
+<device id="generic" user_agent="" fall_back="root">
+	<group id="display"><capability name="resolution_width" value=""/><capability name="colors" value="256"/></group>
+</device>
+<device id="phone" user_agent="Phone/1.0" fall_back="generic">
+	<group id="display"><capability name="resolution_width" value="320"/><capability name="colors" value="many"/></group>
+</device>
+</devices></wurfl>`
+	e := NewEngine(nil)
+	if err := e.LoadXML(strings.NewReader(xml), nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		id, name string
+		want     interface{}
+	}{
+		{"generic", "resolution_width", 0},
+		{"phone", "resolution_width", 320},
+		{"generic", "colors", "256"},
+		{"phone", "colors", "many"},
+	} {
+		if got, _ := e.Find(test.id).Value(test.name); got != test.want {
+			t.Errorf("%s %s = %#v, want %#v", test.id, test.name, got, test.want)
+		}
+	}
+
+	// Devices registered later get the types already in use.
+	if err := e.RegisterDevice("tablet", "Tablet/1.0", false, map[string]interface{}{"resolution_width": "", "colors": "16"}, "generic"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := e.Find("tablet").Value("resolution_width"); got != 0 {
+		t.Errorf("tablet resolution_width = %#v, want 0", got)
+	}
+	if got, _ := e.Find("tablet").Value("colors"); got != "16" {
+		t.Errorf("tablet colors = %#v, want \"16\"", got)
+	}
+}
+
+// TestInheritedCapabilities checks that a device reads the capabilities
+// it does not set from the closest device up its fall_back chain, and
+// that Source names that device.
+func TestInheritedCapabilities(t *testing.T) {
+	for _, flatten := range []bool{false, true} {
+		e := newTestEngine(t, &EngineOptions{FlattenCapabilities: flatten})
+		dev := e.Find("samsung_gt_i9300_ver1")
+		if dev == nil {
+			t.Fatal("samsung_gt_i9300_ver1 is not loaded")
+		}
+		for _, test := range []struct {
+			name, source string
+			want         interface{}
+		}{
+			{"brand_name", "samsung_gt_i9300_ver1", "Samsung"},
+			// Overrides the value of generic_android and generic.
+			{"resolution_width", "samsung_gt_i9300_ver1", 720},
+			{"device_os_version", "generic_android_ver4", "4.0"},
+			{"device_os", "generic_android", "Android"},
+			{"is_wireless_device", "generic_mobile", true},
+			{"resolution_height", "generic", 90},
+			{"is_tablet", "generic", false},
+		} {
+			value, err := dev.Value(test.name)
+			if err != nil || value != test.want {
+				t.Errorf("flatten %v: %s = %#v, %v, want %#v", flatten, test.name, value, err, test.want)
+			}
+			if src := dev.Source(test.name); src == nil || src.Id != test.source {
+				t.Errorf("flatten %v: %s comes from %v, want %s", flatten, test.name, src, test.source)
+			}
+			_, own := dev.Capabilities[test.name]
+			if own != (test.source == dev.Id) {
+				t.Errorf("flatten %v: %s in Capabilities is %v", flatten, test.name, own)
+			}
+		}
+		if _, err := dev.Value("no_such_capability"); !errors.Is(err, ErrUnknownCapability) {
+			t.Errorf("flatten %v: unknown capability: got error %v", flatten, err)
+		}
+		if src := dev.Source("no_such_capability"); src != nil {
+			t.Errorf("flatten %v: unknown capability comes from %s", flatten, src.Id)
+		}
+	}
+}
+
+// TestFlattenedCapabilities checks that flattening the capabilities
+// changes nothing a device reports.
+func TestFlattenedCapabilities(t *testing.T) {
+	lazy := newTestEngine(t, nil)
+	flat := newTestEngine(t, &EngineOptions{FlattenCapabilities: true})
+	devices := 0
+	for _, ua := range testUAs {
+		lazyDev, flatDev := lazy.Match(ua), flat.Match(ua)
+		if lazyDev.Id != flatDev.Id {
+			t.Errorf("%q: got %s and %s", ua, lazyDev.Id, flatDev.Id)
+			continue
+		}
+		for d := lazyDev; d != nil; d = d.Parent {
+			devices++
+			all := d.AllCapabilities()
+			if want := flat.Find(d.Id).AllCapabilities(); !reflect.DeepEqual(all, want) {
+				t.Errorf("%s: lazy capabilities\n\t%v\nflattened\n\t%v", d.Id, all, want)
+			}
+			if want := len(lazy.Find(GENERIC).Capabilities); len(all) < want {
+				t.Errorf("%s: got %d capabilities, generic alone has %d", d.Id, len(all), want)
+			}
+			// The map is a copy.
+			all["brand_name"] = "changed"
+			if value, _ := d.Value("brand_name"); value == "changed" {
+				t.Errorf("%s: AllCapabilities shares its map with the device", d.Id)
+			}
+		}
+	}
+	if devices == 0 {
+		t.Fatal("no devices compared")
+	}
+}
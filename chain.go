@@ -0,0 +1,160 @@
+package wurflgo
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownHandler   = errors.New("Unknown handler")
+	ErrDuplicateHandler = errors.New("Handler already in the chain")
+)
+
+// The methods below change the order of a chain. Filter files a user
+// agent with the first handler that can handle it, so a handler only sees
+// the user agents that no handler before it claims. They are meant for
+// chains that are not in use yet, e.g. in the function given to
+// Engine.CustomizeChain, which also files the loaded user agents again.
+
+// Names returns the names of the handlers of the chain, in order.
+func (c *Chain) Names() []string {
+	names := make([]string, len(c.Handlers))
+	for i, h := range c.Handlers {
+		names[i] = HandlerName(h)
+	}
+	return names
+}
+
+func (c *Chain) index(name string) int {
+	for i, h := range c.Handlers {
+		if HandlerName(h) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// InsertBefore puts h in the chain just before the handler called name.
+func (c *Chain) InsertBefore(name string, h Handlers) error {
+	at := c.index(name)
+	if at < 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownHandler, name)
+	}
+	return c.insert(at, h)
+}
+
+// InsertAfter puts h in the chain just after the handler called name.
+func (c *Chain) InsertAfter(name string, h Handlers) error {
+	at := c.index(name)
+	if at < 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownHandler, name)
+	}
+	return c.insert(at+1, h)
+}
+
+func (c *Chain) insert(at int, h Handlers) error {
+	if c.index(HandlerName(h)) >= 0 {
+		return fmt.Errorf("%w: %s", ErrDuplicateHandler, HandlerName(h))
+	}
+	c.Handlers = append(c.Handlers, nil)
+	copy(c.Handlers[at+1:], c.Handlers[at:])
+	c.Handlers[at] = h
+	c.relink()
+	return nil
+}
+
+// Remove takes the handler called name out of the chain. The user agents
+// it claimed go to the handlers after it.
+func (c *Chain) Remove(name string) error {
+	at := c.index(name)
+	if at < 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownHandler, name)
+	}
+	if len(c.Handlers) == 1 {
+		return errors.New("Cannot remove the last handler of a chain")
+	}
+	c.Handlers = append(c.Handlers[:at], c.Handlers[at+1:]...)
+	c.relink()
+	return nil
+}
+
+// Replace puts h in the place of the handler called name.
+func (c *Chain) Replace(name string, h Handlers) error {
+	at := c.index(name)
+	if at < 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownHandler, name)
+	}
+	if other := c.index(HandlerName(h)); other >= 0 && other != at {
+		return fmt.Errorf("%w: %s", ErrDuplicateHandler, HandlerName(h))
+	}
+	c.Handlers[at] = h
+	c.relink()
+	return nil
+}
+
+// CustomizeChain changes the handler chain of the engine with fn and
+// files the user agents of the loaded devices again, so every handler
+// holds the user agents it now claims. fn gets the chain the engine would
+// build for new data, with EngineOptions.HandlerRules applied, and is
+// kept: Reload calls it again, after the functions of earlier calls, for
+// every chain it builds. fn should therefore create the handlers it
+// adds.
+//
+//	err := e.CustomizeChain(func(c *wurflgo.Chain) error {
+//		return c.InsertBefore("AndroidHandler", NewInHouseAppHandler(wurflgo.CreateGenericNormalizers()))
+//	})
+//
+// If fn fails the engine keeps its chain. Matches already running finish
+// with the old chain.
+func (e *Engine) CustomizeChain(fn func(c *Chain) error) error {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	if e.rulesErr != nil {
+		return e.rulesErr
+	}
+	staging := &Engine{engineConfig: e.engineConfig}
+	staging.customize = append(append([]func(*Chain) error{}, e.customize...), fn)
+	if err := e.rechain(staging); err != nil {
+		return err
+	}
+	e.customize = staging.customize
+	return nil
+}
+
+// CustomizeChain changes the handler chain of the default engine.
+func CustomizeChain(fn func(c *Chain) error) error {
+	return defaultEngine.CustomizeChain(fn)
+}
+
+// rechain files the devices of e with the chain staging builds and makes
+// it the chain of e. The caller holds reloadMu and keeps the
+// configuration of staging if it succeeds.
+func (e *Engine) rechain(staging *Engine) error {
+	next, err := staging.newDataset()
+	if err != nil {
+		return err
+	}
+	if err := e.current().refile(next); err != nil {
+		return err
+	}
+	next.freeze()
+	e.data.Store(next)
+	e.cache.purge()
+	return nil
+}
+
+// refile registers the devices of ds with next, parents first.
+func (ds *dataset) refile(next *dataset) error {
+	ds.rlock()
+	defer ds.mu.RUnlock()
+	for _, dev := range ds.repo.ordered() {
+		parent := ""
+		if dev.Parent != nil {
+			parent = dev.Parent.Id
+		}
+		if err := next.register(dev.Id, dev.UA, dev.ActualDeviceRoot, dev.Capabilities, parent, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package wurflgo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func testRuleHandler(t *testing.T, name string) *RuleHandler {
+	t.Helper()
+	h, err := HandlerRule{Name: name, Contains: []string{"GT-I9300"}, Tolerance: "length"}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestChainEdits(t *testing.T) {
+	c := NewDefaultChain(NewUtil())
+	names := c.Names()
+	at := c.index("AndroidHandler")
+
+	if err := c.InsertBefore("AndroidHandler", testRuleHandler(t, "BeforeHandler")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.InsertAfter("AndroidHandler", testRuleHandler(t, "AfterHandler")); err != nil {
+		t.Fatal(err)
+	}
+	want := append(append(append([]string{}, names[:at]...), "BeforeHandler", "AndroidHandler", "AfterHandler"), names[at+1:]...)
+	if got := c.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("after inserts: got %v, want %v", got, want)
+	}
+
+	if err := c.Replace("AfterHandler", testRuleHandler(t, "ReplacedHandler")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Remove("BeforeHandler"); err != nil {
+		t.Fatal(err)
+	}
+	want = append(append(append([]string{}, names[:at]...), "AndroidHandler", "ReplacedHandler"), names[at+1:]...)
+	if got := c.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("after replace and remove: got %v, want %v", got, want)
+	}
+
+	// The handlers are linked in the order of the chain.
+	c.Filter(testSamsungUA, "samsung")
+	if h := c.Handler("ReplacedHandler").(*RuleHandler); len(h.UASWithDeviceId) != 0 {
+		t.Errorf("ReplacedHandler got %v, AndroidHandler comes first", h.UASWithDeviceId)
+	}
+	if h := c.Handler("AndroidHandler").(*AndroidHandler); len(h.UASWithDeviceId) != 1 {
+		t.Errorf("AndroidHandler got %v", h.UASWithDeviceId)
+	}
+
+	for _, test := range []struct {
+		err  error
+		want error
+		msg  string
+	}{
+		{c.InsertBefore("NoSuchHandler", testRuleHandler(t, "X")), ErrUnknownHandler, "Unknown handler: NoSuchHandler"},
+		{c.InsertAfter("NoSuchHandler", testRuleHandler(t, "X")), ErrUnknownHandler, "Unknown handler: NoSuchHandler"},
+		{c.Remove("NoSuchHandler"), ErrUnknownHandler, "Unknown handler: NoSuchHandler"},
+		{c.Replace("NoSuchHandler", testRuleHandler(t, "X")), ErrUnknownHandler, "Unknown handler: NoSuchHandler"},
+		{c.InsertBefore("AndroidHandler", testRuleHandler(t, "ReplacedHandler")), ErrDuplicateHandler, "Handler already in the chain: ReplacedHandler"},
+		{c.Replace("AndroidHandler", testRuleHandler(t, "ReplacedHandler")), ErrDuplicateHandler, "Handler already in the chain: ReplacedHandler"},
+	} {
+		if !errors.Is(test.err, test.want) || test.err.Error() != test.msg {
+			t.Errorf("got %v, want %s", test.err, test.msg)
+		}
+	}
+
+	single := &Chain{util: NewUtil()}
+	if err := single.insert(0, testRuleHandler(t, "OnlyHandler")); err != nil {
+		t.Fatal(err)
+	}
+	if err := single.Remove("OnlyHandler"); err == nil {
+		t.Error("removed the last handler")
+	}
+}
+
+// TestCustomizeChainRefiles checks that the loaded user agents follow the
+// handlers of the chain as it is edited.
+func TestCustomizeChainRefiles(t *testing.T) {
+	e := newTestEngine(t, nil)
+	iPhoneUA := "Mozilla/5.0 (iPhone; U; CPU iPhone OS 4_0 like Mac OS X; en-us) AppleWebKit/532.9 (KHTML, like Gecko) Version/4.0.5 Mobile/8A293 Safari/6531.22.7"
+
+	for _, test := range []struct {
+		name    string
+		fn      func(c *Chain) error
+		ua      string
+		handler string
+	}{
+		{"insert", func(c *Chain) error {
+			return c.InsertBefore("AndroidHandler", testRuleHandler(t, "GT9300Handler"))
+		}, testSamsungUA, "GT9300Handler"},
+		{"replace", func(c *Chain) error {
+			return c.Replace("GT9300Handler", testRuleHandler(t, "GalaxyHandler"))
+		}, testSamsungUA, "GalaxyHandler"},
+		{"remove", func(c *Chain) error {
+			return c.Remove("GalaxyHandler")
+		}, testSamsungUA, "AndroidHandler"},
+		{"remove apple", func(c *Chain) error {
+			return c.Remove("AppleHandler")
+		}, iPhoneUA, ""},
+	} {
+		if err := e.CustomizeChain(test.fn); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		res := e.Explain(test.ua)
+		if test.handler != "" && res.Handler != test.handler {
+			t.Errorf("%s: got handler %s, want %s", test.name, res.Handler, test.handler)
+		}
+		if test.handler == "" && res.Handler == "AppleHandler" {
+			t.Errorf("%s: still matched by AppleHandler", test.name)
+		}
+		if res.Device == nil || res.Fallback {
+			t.Errorf("%s: got %s by %s/%s, want the loaded device", test.name, res.DeviceId, res.Handler, res.Stage)
+		}
+	}
+
+	// A failing function leaves the chain as it is.
+	names := e.Chain().Names()
+	if err := e.CustomizeChain(func(c *Chain) error { return c.Remove("NoSuchHandler") }); !errors.Is(err, ErrUnknownHandler) {
+		t.Errorf("got %v, want ErrUnknownHandler", err)
+	}
+	if got := e.Chain().Names(); !reflect.DeepEqual(got, names) {
+		t.Errorf("failed CustomizeChain changed the chain to %v", got)
+	}
+}
+
+// TestChainUtil checks that the Util of EngineOptions reaches every
+// handler of the chain and the normalizers that use one.
+func TestChainUtil(t *testing.T) {
+	u := NewUtil()
+	e := NewEngine(&EngineOptions{Util: u})
+	for _, h := range e.Chain().Handlers {
+		if got := h.(interface{ Util() *Util }).Util(); got != u {
+			t.Errorf("%s has another Util", HandlerName(h))
+		}
+		norm, ok := h.GetNormalizer().(*UserAgentNormalizer)
+		if !ok {
+			continue
+		}
+		for _, n := range norm.normalizers {
+			var got *Util
+			switch n := n.(type) {
+			case *LocaleRemover:
+				got = n.util
+			case *Opera:
+				got = n.util
+			case *Android:
+				got = n.handler.Util()
+			case *Kindle:
+				got = n.handler.Util()
+			case *HTCMac:
+				got = n.handler.Util()
+			case *WebOS:
+				got = n.handler.Util()
+			default:
+				continue
+			}
+			if got != u {
+				t.Errorf("%s: %T has another Util", HandlerName(h), n)
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package wurflgo
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// User-Agent Client Hints request headers.
+const (
+	HeaderSecCHUA                = "Sec-CH-UA"
+	HeaderSecCHUAMobile          = "Sec-CH-UA-Mobile"
+	HeaderSecCHUAModel           = "Sec-CH-UA-Model"
+	HeaderSecCHUAPlatform        = "Sec-CH-UA-Platform"
+	HeaderSecCHUAPlatformVersion = "Sec-CH-UA-Platform-Version"
+	HeaderSecCHUAFullVersionList = "Sec-CH-UA-Full-Version-List"
+)
+
+// AcceptCH lists the high-entropy client hints the engine uses. Browsers
+// only send them after the server asked for them in an Accept-CH response
+// header; Sec-CH-UA, Sec-CH-UA-Mobile and Sec-CH-UA-Platform are always
+// sent.
+var AcceptCH = []string{
+	HeaderSecCHUAModel,
+	HeaderSecCHUAPlatformVersion,
+	HeaderSecCHUAFullVersionList,
+}
+
+// AcceptCHHeader returns the value of the Accept-CH response header that
+// asks browsers for the hints in AcceptCH.
+func AcceptCHHeader() string {
+	return strings.Join(AcceptCH, ", ")
+}
+
+// Brand is one entry of Sec-CH-UA or Sec-CH-UA-Full-Version-List.
+type Brand struct {
+	Name    string
+	Version string
+}
+
+// ClientHints holds the User-Agent Client Hints of a request.
+type ClientHints struct {
+	// Brands comes from Sec-CH-UA-Full-Version-List when it is sent and
+	// from Sec-CH-UA, which only has major versions, otherwise.
+	Brands          []Brand
+	Mobile          bool
+	Model           string
+	Platform        string
+	PlatformVersion string
+}
+
+// ParseClientHints reads the client hints of a request. It returns nil
+// when the request has none.
+func ParseClientHints(h http.Header) *ClientHints {
+	brands := h.Get(HeaderSecCHUAFullVersionList)
+	if brands == "" {
+		brands = h.Get(HeaderSecCHUA)
+	}
+	platform := h.Get(HeaderSecCHUAPlatform)
+	if brands == "" && platform == "" {
+		return nil
+	}
+	return &ClientHints{
+		Brands:          parseBrands(brands),
+		Mobile:          h.Get(HeaderSecCHUAMobile) == "?1",
+		Model:           unquoteHint(h.Get(HeaderSecCHUAModel)),
+		Platform:        unquoteHint(platform),
+		PlatformVersion: unquoteHint(h.Get(HeaderSecCHUAPlatformVersion)),
+	}
+}
+
+// parseBrands reads a brand list such as
+// "Chromium";v="114.0.5735.199", "Not.A/Brand";v="24.0.0.0".
+func parseBrands(list string) []Brand {
+	brands := []Brand{}
+	for _, item := range strings.Split(list, ",") {
+		parts := strings.Split(item, ";")
+		b := Brand{Name: unquoteHint(parts[0])}
+		for _, param := range parts[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "v=") {
+				b.Version = unquoteHint(param[2:])
+			}
+		}
+		if b.Name != "" {
+			brands = append(brands, b)
+		}
+	}
+	return brands
+}
+
+func unquoteHint(s string) string {
+	s = strings.TrimSpace(s)
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return strings.Trim(s, `"`)
+}
+
+// Brand returns the version of the named brand, or "" if it is not
+// listed.
+func (ch *ClientHints) Brand(name string) string {
+	for _, b := range ch.Brands {
+		if b.Name == name {
+			return b.Version
+		}
+	}
+	return ""
+}
+
+var (
+	chromeVersionRx = regexp.MustCompile(`Chrome/[\d.]+`)
+	edgeVersionRx   = regexp.MustCompile(`Edg/[\d.]+`)
+	operaVersionRx  = regexp.MustCompile(`OPR/[\d.]+`)
+	androidRx       = regexp.MustCompile(`Android ([\d.]+)(; [^;)]+)?\)`)
+	windowsNTRx     = regexp.MustCompile(`Windows NT [\d.]+`)
+	macOSVersionRx  = regexp.MustCompile(`Mac OS X [\d_]+`)
+	// Windows 10 and 11 both keep Windows NT 10.0; only the releases
+	// before them have their own NT version.
+	windowsReleasesNT = map[string]string{"0.1": "6.1", "0.2": "6.2", "0.3": "6.3"}
+)
+
+// UserAgent rebuilds the details that Chromium browsers leave out of
+// their reduced User-Agent header from the hints: the Android version
+// and device model, the Windows and macOS versions and the full browser
+// version. The handlers then see the same user agent the browser sent
+// before the reduction. The model is written with the locale placeholder
+// and Build/ token AndroidHandler.GetAndroidModel reads it from, e.g.
+// "Android 13; xx-xx; Pixel 7 Build/". User agents of other browsers are
+// returned unchanged.
+func (ch *ClientHints) UserAgent(ua string) string {
+	if !strings.Contains(ua, "Chrome/") {
+		return ua
+	}
+	if v := ch.Brand("Google Chrome"); v != "" {
+		ua = chromeVersionRx.ReplaceAllLiteralString(ua, "Chrome/"+v)
+	} else if v := ch.Brand("Chromium"); v != "" {
+		ua = chromeVersionRx.ReplaceAllLiteralString(ua, "Chrome/"+v)
+	}
+	if v := ch.Brand("Microsoft Edge"); v != "" {
+		ua = edgeVersionRx.ReplaceAllLiteralString(ua, "Edg/"+v)
+	}
+	if v := ch.Brand("Opera"); v != "" {
+		ua = operaVersionRx.ReplaceAllLiteralString(ua, "OPR/"+v)
+	}
+	switch ch.Platform {
+	case "Android":
+		if m := androidRx.FindStringSubmatch(ua); m != nil {
+			version, model := m[1], m[2]
+			if ch.PlatformVersion != "" {
+				version = strings.TrimSuffix(strings.TrimSuffix(ch.PlatformVersion, ".0"), ".0")
+			}
+			if ch.Model != "" {
+				model = "; xx-xx; " + ch.Model + " Build/"
+			}
+			ua = strings.Replace(ua, m[0], "Android "+version+model+")", 1)
+		}
+	case "Windows":
+		major := strings.SplitN(ch.PlatformVersion, ".", 3)
+		if len(major) >= 2 {
+			if nt, found := windowsReleasesNT[major[0]+"."+major[1]]; found {
+				ua = windowsNTRx.ReplaceAllLiteralString(ua, "Windows NT "+nt)
+			}
+		}
+	case "macOS":
+		if ch.PlatformVersion != "" {
+			ua = macOSVersionRx.ReplaceAllLiteralString(ua, "Mac OS X "+strings.Replace(ch.PlatformVersion, ".", "_", -1))
+		}
+	}
+	return ua
+}
@@ -0,0 +1,135 @@
+package wurflgo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const testReducedAndroidUA = "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.0.0 Mobile Safari/537.36"
+
+func TestParseClientHints(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		headers map[string]string
+		want    *ClientHints
+	}{
+		{"none", map[string]string{"User-Agent": testReducedAndroidUA}, nil},
+		{"Chrome on Android", map[string]string{
+			HeaderSecCHUA:                `"Not.A/Brand";v="8", "Chromium";v="114", "Google Chrome";v="114"`,
+			HeaderSecCHUAMobile:          "?1",
+			HeaderSecCHUAPlatform:        `"Android"`,
+			HeaderSecCHUAModel:           `"Pixel 7"`,
+			HeaderSecCHUAPlatformVersion: `"13.0.0"`,
+			HeaderSecCHUAFullVersionList: `"Not.A/Brand";v="8.0.0.0", "Chromium";v="114.0.5735.196", "Google Chrome";v="114.0.5735.196"`,
+		}, &ClientHints{
+			Brands:          []Brand{{"Not.A/Brand", "8.0.0.0"}, {"Chromium", "114.0.5735.196"}, {"Google Chrome", "114.0.5735.196"}},
+			Mobile:          true,
+			Model:           "Pixel 7",
+			Platform:        "Android",
+			PlatformVersion: "13.0.0",
+		}},
+		// Without the high-entropy hints only Sec-CH-UA and the platform
+		// are known, and the model is sent empty.
+		{"Edge on Windows, low entropy", map[string]string{
+			HeaderSecCHUA:         `"Microsoft Edge";v="119", "Chromium";v="119", "Not?A_Brand";v="24"`,
+			HeaderSecCHUAMobile:   "?0",
+			HeaderSecCHUAPlatform: `"Windows"`,
+			HeaderSecCHUAModel:    `""`,
+		}, &ClientHints{
+			Brands:   []Brand{{"Microsoft Edge", "119"}, {"Chromium", "119"}, {"Not?A_Brand", "24"}},
+			Platform: "Windows",
+		}},
+		// Values some proxies pass on unquoted, with escaped quotes.
+		{"unquoted", map[string]string{
+			HeaderSecCHUA:                `Chromium;v=114, "Brand \"X\"";v="1"`,
+			HeaderSecCHUAPlatform:        "macOS",
+			HeaderSecCHUAPlatformVersion: "13.4.1",
+		}, &ClientHints{
+			Brands:          []Brand{{"Chromium", "114"}, {`Brand "X"`, "1"}},
+			Platform:        "macOS",
+			PlatformVersion: "13.4.1",
+		}},
+		{"platform only", map[string]string{HeaderSecCHUAPlatform: `"Linux"`}, &ClientHints{Brands: []Brand{}, Platform: "Linux"}},
+	} {
+		h := http.Header{}
+		for name, value := range test.headers {
+			h.Set(name, value)
+		}
+		if got := ParseClientHints(h); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestClientHintsUserAgent(t *testing.T) {
+	android := &AndroidHandler{}
+	for _, test := range []struct {
+		name  string
+		ch    ClientHints
+		ua    string
+		want  string
+		model string
+	}{
+		{"Android model and version", ClientHints{
+			Brands:          []Brand{{"Chromium", "114.0.5735.196"}, {"Google Chrome", "114.0.5735.196"}},
+			Model:           "Pixel 7",
+			Platform:        "Android",
+			PlatformVersion: "13.0.0",
+		}, testReducedAndroidUA,
+			"Mozilla/5.0 (Linux; Android 13; xx-xx; Pixel 7 Build/) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.5735.196 Mobile Safari/537.36",
+			"Pixel 7"},
+		{"Android without model", ClientHints{Platform: "Android", PlatformVersion: "12.1.0"}, testReducedAndroidUA,
+			"Mozilla/5.0 (Linux; Android 12.1; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.0.0 Mobile Safari/537.36",
+			NO_MATCH},
+		{"Windows 11", ClientHints{
+			Brands:          []Brand{{"Microsoft Edge", "119.0.2151.58"}, {"Chromium", "119.0.6045.160"}},
+			Platform:        "Windows",
+			PlatformVersion: "15.0.0",
+		}, "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36 Edg/119.0.0.0",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.6045.160 Safari/537.36 Edg/119.0.2151.58",
+			NO_MATCH},
+		{"Windows 7", ClientHints{Platform: "Windows", PlatformVersion: "0.1.0"},
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/109.0.0.0 Safari/537.36",
+			"Mozilla/5.0 (Windows NT 6.1; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/109.0.0.0 Safari/537.36",
+			NO_MATCH},
+		{"macOS", ClientHints{Platform: "macOS", PlatformVersion: "13.4.1"},
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.0.0 Safari/537.36",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 13_4_1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/114.0.0.0 Safari/537.36",
+			NO_MATCH},
+		{"not Chromium", ClientHints{Platform: "Android", Model: "Pixel 7"},
+			"Mozilla/5.0 (Android 13; Mobile; rv:120.0) Gecko/120.0 Firefox/120.0",
+			"Mozilla/5.0 (Android 13; Mobile; rv:120.0) Gecko/120.0 Firefox/120.0",
+			NO_MATCH},
+	} {
+		got := test.ch.UserAgent(test.ua)
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+		if model := android.GetAndroidModel(got); model != test.model {
+			t.Errorf("%s: GetAndroidModel gives %q, want %q", test.name, model, test.model)
+		}
+	}
+}
+
+// TestMatchRequestClientHints checks that the model of Sec-CH-UA-Model
+// finds the device of a reduced Android user agent.
+func TestMatchRequestClientHints(t *testing.T) {
+	e := newTestEngine(t, nil)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("User-Agent", testReducedAndroidUA)
+	req.Header.Set(HeaderSecCHUA, `"Chromium";v="114", "Google Chrome";v="114"`)
+	req.Header.Set(HeaderSecCHUAMobile, "?1")
+	req.Header.Set(HeaderSecCHUAPlatform, `"Android"`)
+	req.Header.Set(HeaderSecCHUAPlatformVersion, `"4.0.4"`)
+	req.Header.Set(HeaderSecCHUAModel, `"GT-I9300"`)
+	if dev := e.MatchRequest(req); dev == nil || dev.Id != "samsung_gt_i9300_ver1" {
+		t.Errorf("got %v, want samsung_gt_i9300_ver1", dev)
+	}
+
+	ignoring := newTestEngine(t, &EngineOptions{IgnoreClientHints: true})
+	if dev := ignoring.MatchRequest(req); dev == nil || dev.Id == "samsung_gt_i9300_ver1" {
+		t.Errorf("IgnoreClientHints: got %v", dev)
+	}
+}
@@ -0,0 +1,62 @@
+// wurfld serves device detection over HTTP from a wurfl.xml file or a
+// snapshot, so that services written in other languages can share one
+// detection source. It does not need network access besides the port it
+// listens on.
+//
+//	wurfld -input wurfl.xml -patch patch.xml -groups product_info,display -listen :8080
+//	wurfld -snapshot wurfl.snapshot
+//
+// Endpoints:
+//
+//	GET  /match?ua=...&capabilities=a,b&explain=true
+//	POST /match   {"headers": {"User-Agent": "...", "X-OperaMini-Phone-UA": "..."}, "capabilities": ["a"]}
+//	POST /batch   {"user_agents": ["...", "..."], "capabilities": ["a"]}
+//	GET  /device/{id}?capabilities=a,b
+//	GET  /healthz
+//
+// Capabilities may name virtual capabilities such as form_factor. Send
+// SIGHUP to reload the data and the -rules file from the same files.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/srinathgs/wurflgo"
+	"github.com/srinathgs/wurflgo/internal/cli"
+)
+
+func main() {
+	data := cli.DataFlags()
+	listen := flag.String("listen", ":8080", "Address to listen on")
+	cache := flag.Int("cache", 10000, "Number of distinct user agents whose match is cached, 0 to disable")
+	flag.Parse()
+	if err := data.Check(); err != nil {
+		log.Fatal(err)
+	}
+
+	e := wurflgo.NewEngine(&wurflgo.EngineOptions{
+		CacheSize: *cache,
+		OnReload: func(ev wurflgo.ReloadEvent) {
+			log.Printf("reload: err=%v devices=%d (was %d) in %s", ev.Err, ev.Devices, ev.PreviousDevices, ev.Duration)
+		},
+	})
+	if err := e.Reload(data.Load); err != nil {
+		log.Fatal(err)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			e.Reload(data.Load)
+		}
+	}()
+
+	log.Printf("listening on %s", *listen)
+	log.Fatal(http.ListenAndServe(*listen, NewServer(e)))
+}
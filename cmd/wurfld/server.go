@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/srinathgs/wurflgo"
+)
+
+// maxBatch bounds the number of user agents of one POST /batch request.
+const maxBatch = 1000
+
+// maxBody bounds the size of request bodies, in bytes.
+const maxBody = 1 << 20
+
+// Server answers device detection requests from one engine. It is an
+// http.Handler, so it can be tested with net/http/httptest.
+type Server struct {
+	Engine *wurflgo.Engine
+	mux    *http.ServeMux
+}
+
+func NewServer(e *wurflgo.Engine) *Server {
+	s := &Server{Engine: e, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/match", s.handleMatch)
+	s.mux.HandleFunc("/batch", s.handleBatch)
+	s.mux.HandleFunc("/device/", s.handleDevice)
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// DeviceResponse is the JSON form of a device. Capabilities holds the
+// requested capabilities, own, inherited and virtual, or every real
+// capability when none were requested.
+type DeviceResponse struct {
+	Id           string                 `json:"id"`
+	UserAgent    string                 `json:"user_agent,omitempty"`
+	Fallback     string                 `json:"fallback_from,omitempty"`
+	Handler      string                 `json:"handler,omitempty"`
+	Stage        string                 `json:"stage,omitempty"`
+	Capabilities map[string]interface{} `json:"capabilities"`
+}
+
+// MatchRequest is the body of POST /match: the headers of the request to
+// match, as they would reach a web server.
+type MatchRequest struct {
+	Headers      map[string]string `json:"headers"`
+	Capabilities []string          `json:"capabilities"`
+}
+
+// BatchRequest is the body of POST /batch.
+type BatchRequest struct {
+	UserAgents   []string `json:"user_agents"`
+	Capabilities []string `json:"capabilities"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// GET /match?ua=...&capabilities=a,b&explain=true
+// POST /match with a MatchRequest body
+func (s *Server) handleMatch(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		ua := q.Get("ua")
+		if ua == "" {
+			ua = r.UserAgent()
+		}
+		writeJSON(w, http.StatusOK, s.match(ua, splitList(q.Get("capabilities")), q.Get("explain") == "true"))
+	case http.MethodPost:
+		req := MatchRequest{}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		hr, _ := http.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range req.Headers {
+			hr.Header.Set(k, v)
+		}
+		ua := s.Engine.RequestUA(hr)
+		res := s.response(s.Engine.MatchRequest(hr), ua, req.Capabilities)
+		writeJSON(w, http.StatusOK, res)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"Method not allowed"})
+	}
+}
+
+// POST /batch with a BatchRequest body answers a list of DeviceResponse
+// in the order of the user agents.
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"Method not allowed"})
+		return
+	}
+	req := BatchRequest{}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if len(req.UserAgents) > maxBatch {
+		writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse{"Too many user agents"})
+		return
+	}
+	res := make([]*DeviceResponse, len(req.UserAgents))
+	for i, ua := range req.UserAgents {
+		res[i] = s.match(ua, req.Capabilities, false)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// GET /device/{id}?capabilities=a,b
+func (s *Server) handleDevice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"Method not allowed"})
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/device/")
+	dev := s.Engine.Find(id)
+	if dev == nil {
+		writeJSON(w, http.StatusNotFound, errorResponse{"Unknown device " + id})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.response(dev, dev.UA, splitList(r.URL.Query().Get("capabilities"))))
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if s.Engine.Find(wurflgo.GENERIC) == nil {
+		writeJSON(w, http.StatusServiceUnavailable, errorResponse{"No devices loaded"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) match(ua string, capabilities []string, explain bool) *DeviceResponse {
+	if explain {
+		mr := s.Engine.Explain(ua)
+		res := s.response(mr.Device, ua, capabilities)
+		res.Handler, res.Stage = mr.Handler, mr.Stage
+		if mr.Fallback {
+			res.Fallback = mr.DeviceId
+		}
+		return res
+	}
+	dev, err := s.Engine.MatchE(ua)
+	res := s.response(dev, ua, capabilities)
+	var fb *wurflgo.FallbackError
+	if errors.As(err, &fb) {
+		res.Fallback = fb.Requested
+	}
+	return res
+}
+
+func (s *Server) response(dev *wurflgo.Device, ua string, capabilities []string) *DeviceResponse {
+	res := &DeviceResponse{UserAgent: ua, Capabilities: map[string]interface{}{}}
+	if dev == nil {
+		return res
+	}
+	res.Id = dev.Id
+	if len(capabilities) == 0 {
+		res.Capabilities = dev.AllCapabilities()
+		return res
+	}
+	for _, name := range capabilities {
+		if v, err := dev.Value(name); err == nil {
+			res.Capabilities[name] = v
+		} else if v, err := s.Engine.VirtualCapability(dev, ua, name); err == nil {
+			res.Capabilities[name] = v
+		}
+	}
+	return res
+}
+
+func splitList(list string) []string {
+	names := []string{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// readJSON decodes the body of r, of at most maxBody bytes, into v. It
+// answers the request with an error and returns false if it cannot.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody)).Decode(v)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &tooLarge):
+		writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse{"Request body too large"})
+	default:
+		writeJSON(w, http.StatusBadRequest, errorResponse{"Invalid JSON body: " + err.Error()})
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
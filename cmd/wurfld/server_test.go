@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/srinathgs/wurflgo"
+)
+
+const iPhoneUA = "Mozilla/5.0 (iPhone; U; CPU iPhone OS 4_0 like Mac OS X; en-us) AppleWebKit/532.9 (KHTML, like Gecko) Version/4.0.5 Mobile/8A293 Safari/6531.22.7"
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	f, err := os.Open("../../testdata/wurfl.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	e := wurflgo.NewEngine(nil)
+	if err := e.LoadXML(f, nil); err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(e)
+}
+
+// serve sends a request to s and decodes the JSON response into v.
+func serve(t *testing.T, s *Server, method, target, body string, v interface{}) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s %s: Content-Type %q", method, target, ct)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("%s %s: %s in %q", method, target, err, w.Body.String())
+	}
+	return w.Code
+}
+
+func TestMatchGet(t *testing.T) {
+	s := newTestServer(t)
+	res := DeviceResponse{}
+	code := serve(t, s, "GET", "/match?ua="+url.QueryEscape(iPhoneUA)+"&capabilities=brand_name,is_ios,unknown&explain=true", "", &res)
+	if code != http.StatusOK || res.Id != "apple_iphone_ver4" || res.Handler != "AppleHandler" || res.Stage == "" {
+		t.Errorf("got %d %+v", code, res)
+	}
+	want := map[string]interface{}{"brand_name": "Apple", "is_ios": true}
+	if !reflect.DeepEqual(res.Capabilities, want) {
+		t.Errorf("capabilities %v, want %v", res.Capabilities, want)
+	}
+}
+
+func TestMatchPost(t *testing.T) {
+	s := newTestServer(t)
+	res := DeviceResponse{}
+	body := `{"headers": {"User-Agent": "` + iPhoneUA + `"}, "capabilities": ["brand_name"]}`
+	if code := serve(t, s, "POST", "/match", body, &res); code != http.StatusOK || res.Id != "apple_iphone_ver4" || res.UserAgent != iPhoneUA {
+		t.Errorf("got %d %+v", code, res)
+	}
+	if !reflect.DeepEqual(res.Capabilities, map[string]interface{}{"brand_name": "Apple"}) {
+		t.Errorf("capabilities %v", res.Capabilities)
+	}
+
+	e := errorResponse{}
+	if code := serve(t, s, "POST", "/match", "{", &e); code != http.StatusBadRequest || e.Error == "" {
+		t.Errorf("bad JSON: got %d %+v", code, e)
+	}
+	e = errorResponse{}
+	large := `{"headers": {"User-Agent": "` + strings.Repeat("x", maxBody) + `"}}`
+	if code := serve(t, s, "POST", "/match", large, &e); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("large body: got %d %+v", code, e)
+	}
+	if code := serve(t, s, "PUT", "/match", "", &e); code != http.StatusMethodNotAllowed {
+		t.Errorf("PUT: got %d", code)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	s := newTestServer(t)
+	res := []DeviceResponse{}
+	body := `{"user_agents": ["` + iPhoneUA + `", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"], "capabilities": ["is_robot"]}`
+	if code := serve(t, s, "POST", "/batch", body, &res); code != http.StatusOK || len(res) != 2 {
+		t.Fatalf("got %d %+v", code, res)
+	}
+	if res[0].Id != "apple_iphone_ver4" || res[0].Capabilities["is_robot"] != false {
+		t.Errorf("first: %+v", res[0])
+	}
+	if res[1].Id != "googlebot" || res[1].Capabilities["is_robot"] != true {
+		t.Errorf("second: %+v", res[1])
+	}
+
+	e := errorResponse{}
+	many := `{"user_agents": [""` + strings.Repeat(`, ""`, maxBatch) + `]}`
+	if code := serve(t, s, "POST", "/batch", many, &e); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("too many user agents: got %d %+v", code, e)
+	}
+	if code := serve(t, s, "GET", "/batch", "", &e); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: got %d", code)
+	}
+}
+
+func TestDevice(t *testing.T) {
+	s := newTestServer(t)
+	res := DeviceResponse{}
+	if code := serve(t, s, "GET", "/device/apple_iphone_ver4?capabilities=brand_name,model_name", "", &res); code != http.StatusOK || res.Id != "apple_iphone_ver4" {
+		t.Errorf("got %d %+v", code, res)
+	}
+	if len(res.Capabilities) != 2 || res.Capabilities["brand_name"] != "Apple" {
+		t.Errorf("capabilities %v", res.Capabilities)
+	}
+
+	res = DeviceResponse{}
+	if code := serve(t, s, "GET", "/device/apple_iphone_ver4", "", &res); code != http.StatusOK || len(res.Capabilities) < 3 {
+		t.Errorf("all capabilities: got %d %v", code, res.Capabilities)
+	}
+
+	e := errorResponse{}
+	if code := serve(t, s, "GET", "/device/no_such_device", "", &e); code != http.StatusNotFound || e.Error != "Unknown device no_such_device" {
+		t.Errorf("unknown device: got %d %+v", code, e)
+	}
+}
@@ -0,0 +1,181 @@
+// wurflmatch prints what wurflgo makes of user agents given as arguments
+// or read from standard input, one per line, which makes it easy to pipe
+// access logs through it.
+//
+//	wurflmatch -input wurfl.xml -capabilities brand_name,model_name,form_factor 'Mozilla/5.0 (iPhone; ...'
+//	cut -f 3 access.tsv | wurflmatch -snapshot wurfl.snapshot -format csv > devices.csv
+//
+// Formats:
+//
+//	text   device id, the id it falls back from, handler, stage, lookup
+//	       method, capabilities and user agent separated by tabs
+//	json   one JSON object per line
+//	csv    a header line, then one record per user agent
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/srinathgs/wurflgo"
+	"github.com/srinathgs/wurflgo/internal/cli"
+)
+
+// Result is one matched user agent, as written by the json format.
+type Result struct {
+	UserAgent    string                 `json:"user_agent"`
+	Id           string                 `json:"id"`
+	Fallback     string                 `json:"fallback_from,omitempty"`
+	Handler      string                 `json:"handler"`
+	Stage        string                 `json:"stage"`
+	Method       string                 `json:"method,omitempty"`
+	Capabilities map[string]interface{} `json:"capabilities,omitempty"`
+}
+
+func main() {
+	data := cli.DataFlags()
+	format := flag.String("format", "text", "Output format: text, json (JSON Lines) or csv")
+	capabilities := flag.String("capabilities", "", "list of capabilities to print separated by commas, virtual ones included")
+	flag.Parse()
+	if err := data.Check(); err != nil {
+		log.Fatal(err)
+	}
+	e := wurflgo.NewEngine(nil)
+	if err := data.Load(e); err != nil {
+		log.Fatal(err)
+	}
+	caps := []string{}
+	if *capabilities != "" {
+		caps = strings.Split(*capabilities, ",")
+	}
+	w, err := newWriter(*format, os.Stdout, caps)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	match := func(ua string) {
+		if err := w.Write(matchUA(e, ua, caps)); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if flag.NArg() > 0 {
+		for _, ua := range flag.Args() {
+			match(ua)
+		}
+	} else {
+		sc := bufio.NewScanner(os.Stdin)
+		sc.Buffer(make([]byte, 64*1024), 1024*1024)
+		for sc.Scan() {
+			if ua := strings.TrimSpace(sc.Text()); ua != "" {
+				match(ua)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func matchUA(e *wurflgo.Engine, ua string, caps []string) *Result {
+	mr := e.Explain(ua)
+	res := &Result{UserAgent: ua, Handler: mr.Handler, Stage: mr.Stage, Method: mr.Method, Capabilities: map[string]interface{}{}}
+	if mr.Fallback {
+		res.Fallback = mr.DeviceId
+	}
+	if mr.Device == nil {
+		return res
+	}
+	res.Id = mr.Device.Id
+	for _, name := range caps {
+		if v, err := mr.Device.Value(name); err == nil {
+			res.Capabilities[name] = v
+		} else if v, err := e.VirtualCapability(mr.Device, ua, name); err == nil {
+			res.Capabilities[name] = v
+		}
+	}
+	return res
+}
+
+type resultWriter interface {
+	Write(res *Result) error
+	Flush() error
+}
+
+func newWriter(format string, out io.Writer, caps []string) (resultWriter, error) {
+	bw := bufio.NewWriter(out)
+	switch format {
+	case "text":
+		return &textWriter{bw, caps}, nil
+	case "json":
+		return &jsonWriter{bw, json.NewEncoder(bw)}, nil
+	case "csv":
+		return &csvWriter{csv.NewWriter(out), caps, false}, nil
+	}
+	return nil, fmt.Errorf("Unknown format %s", format)
+}
+
+type textWriter struct {
+	*bufio.Writer
+	caps []string
+}
+
+func (w *textWriter) Write(res *Result) error {
+	fields := []string{res.Id, res.Fallback, res.Handler, res.Stage, res.Method}
+	for _, name := range w.caps {
+		fields = append(fields, name+"="+format(res.Capabilities[name]))
+	}
+	fields = append(fields, res.UserAgent)
+	_, err := w.WriteString(strings.Join(fields, "\t") + "\n")
+	return err
+}
+
+type jsonWriter struct {
+	*bufio.Writer
+	enc *json.Encoder
+}
+
+func (w *jsonWriter) Write(res *Result) error {
+	return w.enc.Encode(res)
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	caps   []string
+	header bool
+}
+
+func (w *csvWriter) Write(res *Result) error {
+	if !w.header {
+		w.header = true
+		if err := w.w.Write(append([]string{"user_agent", "id", "fallback_from", "handler", "stage", "method"}, w.caps...)); err != nil {
+			return err
+		}
+	}
+	record := []string{res.UserAgent, res.Id, res.Fallback, res.Handler, res.Stage, res.Method}
+	for _, name := range w.caps {
+		record = append(record, format(res.Capabilities[name]))
+	}
+	return w.w.Write(record)
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func format(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/srinathgs/wurflgo"
+	"github.com/srinathgs/wurflgo/internal/cli"
+)
+
+const (
+	samsungUA    = "Mozilla/5.0 (Linux; U; Android 4.0.4; en-gb; GT-I9300 Build/IMM76D) AppleWebKit/534.30 (KHTML, like Gecko) Version/4.0 Mobile Safari/534.30"
+	blackBerryUA = "BlackBerry9700/5.0.0.351 Profile/MIDP-2.1 Configuration/CLDC-1.1 VendorID/123"
+)
+
+var testCaps = []string{"brand_name", "is_android", "unknown"}
+
+func newTestEngine(t *testing.T) *wurflgo.Engine {
+	t.Helper()
+	e := wurflgo.NewEngine(nil)
+	if err := (&cli.Data{Input: "../../testdata/wurfl.xml"}).Load(e); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// write matches the user agents and returns what the writer of format
+// prints for them.
+func write(t *testing.T, format string, uas ...string) string {
+	t.Helper()
+	e := newTestEngine(t)
+	var out bytes.Buffer
+	w, err := newWriter(format, &out, testCaps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ua := range uas {
+		if err := w.Write(matchUA(e, ua, testCaps)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestMatchUA(t *testing.T) {
+	e := newTestEngine(t)
+	for _, want := range []*Result{
+		{samsungUA, "samsung_gt_i9300_ver1", "", "AndroidHandler", wurflgo.StageExact, wurflgo.MethodExact, map[string]interface{}{"brand_name": "Samsung", "is_android": true}},
+		// blackberry_generic_ver5 is not in the test data.
+		{blackBerryUA, "generic_mobile", "blackberry_generic_ver5", "BlackBerryHandler", wurflgo.StageRecovery, "", map[string]interface{}{"brand_name": "", "is_android": false}},
+	} {
+		if res := matchUA(e, want.UserAgent, testCaps); !reflect.DeepEqual(res, want) {
+			t.Errorf("got %+v, want %+v", res, want)
+		}
+	}
+}
+
+func TestWriters(t *testing.T) {
+	text := write(t, "text", samsungUA, blackBerryUA)
+	wantText := strings.Join([]string{
+		"samsung_gt_i9300_ver1\t\tAndroidHandler\tApplyExactMatch\texact\tbrand_name=Samsung\tis_android=true\tunknown=\t" + samsungUA,
+		"generic_mobile\tblackberry_generic_ver5\tBlackBerryHandler\tApplyRecoveryMatch\t\tbrand_name=\tis_android=false\tunknown=\t" + blackBerryUA,
+	}, "\n") + "\n"
+	if text != wantText {
+		t.Errorf("text: got\n%s\nwant\n%s", text, wantText)
+	}
+
+	csv := write(t, "csv", samsungUA, blackBerryUA)
+	wantCSV := strings.Join([]string{
+		"user_agent,id,fallback_from,handler,stage,method,brand_name,is_android,unknown",
+		`"` + samsungUA + `",samsung_gt_i9300_ver1,,AndroidHandler,ApplyExactMatch,exact,Samsung,true,`,
+		blackBerryUA + ",generic_mobile,blackberry_generic_ver5,BlackBerryHandler,ApplyRecoveryMatch,,,false,",
+	}, "\n") + "\n"
+	if csv != wantCSV {
+		t.Errorf("csv: got\n%s\nwant\n%s", csv, wantCSV)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(write(t, "json", samsungUA, blackBerryUA), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("json: got %d lines", len(lines))
+	}
+	res := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(lines[1]), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res["id"] != "generic_mobile" || res["fallback_from"] != "blackberry_generic_ver5" || res["stage"] != wurflgo.StageRecovery {
+		t.Errorf("json: got %s", lines[1])
+	}
+	if _, found := res["method"]; found {
+		t.Errorf("json: got a method without a lookup: %s", lines[1])
+	}
+
+	if _, err := newWriter("xml", &bytes.Buffer{}, nil); err == nil {
+		t.Error("unknown format: no error")
+	}
+}
@@ -0,0 +1,413 @@
+package wurflgo
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EngineOptions configures a new Engine.
+type EngineOptions struct {
+	// Util holds the keyword lists and catch-all ids used by the
+	// handlers. NewUtil() is used when it is nil.
+	Util *Util
+
+	// Schema fixes the type of capability values by name. The type of
+	// the other capabilities is inferred from their value, see
+	// ConvertCapability. DefaultCapabilitySchema is used when it is nil.
+	Schema CapabilitySchema
+
+	// FlattenCapabilities keeps a copy of the inherited capabilities in
+	// every device, so lookups do not walk the fall_back chain. It costs
+	// one map per device holding every capability.
+	FlattenCapabilities bool
+
+	// UAHeaders lists the request headers MatchRequest takes the user
+	// agent from, in order of precedence. DefaultUAHeaders is used when
+	// it is nil.
+	UAHeaders []string
+
+	// IgnoreClientHints makes MatchRequest use the User-Agent header as
+	// it is, without completing it from Sec-CH-UA-* client hints.
+	IgnoreClientHints bool
+
+	// CacheSize, when positive, keeps the results of up to that many
+	// distinct user agents, so repeated user agents skip the handler
+	// chain. Match, MatchE and MatchRequest share the cache; the results
+	// of MatchRequest are keyed by the user agent and the X-Wap-Profile
+	// header. Reload and RegisterDevice invalidate it. See CacheStats.
+	CacheSize int
+
+	// HandlerRules are compiled into handlers and put in the default
+	// chain, see HandlerRule. NewEngineE reports rules that do not pass
+	// ValidateHandlerRules, which LoadHandlerRules already checks. See
+	// also Engine.SetHandlerRules.
+	HandlerRules []HandlerRule
+
+	// OnReload, when set, is called after every Reload with its outcome.
+	OnReload func(ReloadEvent)
+}
+
+// ReloadEvent reports the outcome of Engine.Reload.
+type ReloadEvent struct {
+	// Err is the error returned by the loader, or by a function given to
+	// CustomizeChain. The engine keeps serving the previous data when it
+	// is not nil.
+	Err error
+	// Devices is the number of devices in the new repository, or in the
+	// repository that is still in use when Err is not nil.
+	Devices int
+	// PreviousDevices is the number of devices before the reload.
+	PreviousDevices int
+	// Duration is the time taken to build the new repository.
+	Duration time.Duration
+}
+
+// Engine holds one WURFL dataset together with the handler chain that
+// matches user agents against it. Engines do not share any state, so a
+// process may hold several of them side by side.
+//
+// All methods are safe for concurrent use. Registering devices takes an
+// exclusive lock; matching only takes a shared one once the handler
+// tables have been frozen. Registering and loading also wait for the Reload,
+// CustomizeChain or SetHandlerRules in progress, so the device is not
+// left behind in the data they replace.
+type Engine struct {
+	engineConfig
+
+	data atomic.Value // *dataset
+	// reloadMu is held by the methods that replace the dataset or add
+	// to it, see Reload and RegisterDevice.
+	reloadMu sync.Mutex
+}
+
+// engineConfig is the part of an Engine set from EngineOptions, which a
+// staging engine shares with the engine it reloads.
+type engineConfig struct {
+	util      *Util
+	schema    CapabilitySchema
+	flatten   bool
+	uaHeaders []string
+	onReload  func(ReloadEvent)
+	cache     *matchCache
+	// rules, rulesErr and customize are read and written under
+	// reloadMu once the engine is created.
+	rules []HandlerRule
+	// rulesErr is the error of invalid HandlerRules given to NewEngine,
+	// returned by every load until SetHandlerRules replaces them.
+	rulesErr error
+	// customize holds the functions given to CustomizeChain, in order.
+	customize []func(*Chain) error
+
+	ignoreClientHints bool
+}
+
+// dataset is a repository together with the chain its user agents have
+// been filed into. Reload replaces the whole dataset at once, so a match
+// always sees a repository and chain that belong together.
+type dataset struct {
+	repo  *Repository
+	chain *Chain
+	// uaprof maps UAProf URLs to device ids, see indexUAProf.
+	uaprof map[string]string
+
+	mu     sync.RWMutex
+	frozen bool
+	// gen counts the devices registered, so cached results computed
+	// before a registration are not used after it.
+	gen uint64
+}
+
+var defaultEngine = NewEngine(nil)
+
+var ErrNoDevice = errors.New("No device loaded for the user agent, not even generic")
+
+// FallbackError is returned by MatchE, together with a device, when the
+// device picked for a user agent is not loaded and another one is used
+// in its place.
+type FallbackError struct {
+	UserAgent string
+	Requested string
+	Used      string
+}
+
+func (e *FallbackError) Error() string {
+	return "Device " + e.Requested + " is not loaded, using " + e.Used
+}
+
+// NewEngine creates an engine with an empty repository and the default
+// handler chain, with the handlers of opts.HandlerRules put in. If the
+// rules are invalid the engine has none, and loading data or registering
+// devices returns their error; use NewEngineE to get it right away.
+func NewEngine(opts *EngineOptions) *Engine {
+	e, _ := NewEngineE(opts)
+	return e
+}
+
+// NewEngineE is NewEngine returning the error of invalid
+// opts.HandlerRules, along with the engine NewEngine would return.
+func NewEngineE(opts *EngineOptions) (*Engine, error) {
+	e := new(Engine)
+	if opts != nil && opts.Util != nil {
+		e.util = opts.Util
+	} else {
+		e.util = NewUtil()
+	}
+	e.schema = DefaultCapabilitySchema
+	e.uaHeaders = DefaultUAHeaders
+	if opts != nil {
+		if opts.Schema != nil {
+			e.schema = opts.Schema
+		}
+		e.flatten = opts.FlattenCapabilities
+		if opts.UAHeaders != nil {
+			e.uaHeaders = opts.UAHeaders
+		}
+		e.ignoreClientHints = opts.IgnoreClientHints
+		e.onReload = opts.OnReload
+		e.cache = newMatchCache(opts.CacheSize)
+		e.rules = opts.HandlerRules
+	}
+	err := ValidateHandlerRules(e.rules)
+	if err != nil {
+		e.rules = nil
+	}
+	// The rules have been checked and nothing has been customized yet.
+	ds, _ := e.newDataset()
+	e.data.Store(ds)
+	e.rulesErr = err
+	return e, err
+}
+
+// newDataset returns an empty dataset with the engine's handler chain.
+func (e *Engine) newDataset() (*dataset, error) {
+	repo := NewRepository()
+	repo.flatten = e.flatten
+	chain := NewDefaultChain(e.util)
+	if err := chain.ApplyRules(e.rules); err != nil {
+		return nil, err
+	}
+	for _, fn := range e.customize {
+		if err := fn(chain); err != nil {
+			return nil, err
+		}
+	}
+	return &dataset{repo: repo, chain: chain}, nil
+}
+
+func (e *Engine) current() *dataset {
+	return e.data.Load().(*dataset)
+}
+
+// DefaultEngine returns the engine behind the package level functions
+// such as Match and RegisterDevice.
+func DefaultEngine() *Engine {
+	return defaultEngine
+}
+
+func (e *Engine) Repository() *Repository {
+	return e.current().repo
+}
+
+func (e *Engine) Chain() *Chain {
+	return e.current().chain
+}
+
+func (e *Engine) Util() *Util {
+	return e.util
+}
+
+// RegisterDevice adds a device to the engine's repository and files its
+// user agent with the handler that claims it. The parent must already be
+// registered. String capability values are converted to bool, int or
+// float64 following the engine's schema or, for the capabilities it does
+// not list, to the type earlier devices hold them as. See
+// ConvertCapability.
+func (e *Engine) RegisterDevice(id, ua string, actualDeviceRoot bool, capabilities map[string]interface{}, parent string) error {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	if e.rulesErr != nil {
+		return e.rulesErr
+	}
+	return e.current().register(id, ua, actualDeviceRoot, capabilities, parent, e.schema)
+}
+
+// LoadXML registers every device of a wurfl.xml document with the engine,
+// after applying the given wurfl_patch.xml documents in order.
+func (e *Engine) LoadXML(r io.Reader, opts *LoadOptions, patches ...io.Reader) error {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	if err := loadXML(e, r, opts, patches); err != nil {
+		return err
+	}
+	e.current().freeze()
+	return nil
+}
+
+// Reload builds a complete new repository and chain by calling load with
+// an empty staging engine, then swaps them in. Matches already running
+// finish against the old data; matches started after Reload returns see
+// the new data. If load fails the engine keeps its current data.
+//
+//	err := e.Reload(func(s *wurflgo.Engine) error {
+//		return s.LoadXML(f, nil)
+//	})
+func (e *Engine) Reload(load func(staging *Engine) error) error {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	start := time.Now()
+	previous := e.current().repo.count()
+	staging := &Engine{engineConfig: e.engineConfig}
+	ds, err := e.newDataset()
+	if err == nil {
+		staging.data.Store(ds)
+		err = load(staging)
+	}
+	event := ReloadEvent{Err: err, PreviousDevices: previous, Devices: previous}
+	if err == nil {
+		next := staging.current()
+		next.freeze()
+		// The rules may have been replaced with SetHandlerRules.
+		e.rules, e.rulesErr = staging.rules, staging.rulesErr
+		e.data.Store(next)
+		e.cache.purge()
+		event.Devices = next.repo.count()
+	}
+	event.Duration = time.Since(start)
+	if e.onReload != nil {
+		e.onReload(event)
+	}
+	return err
+}
+
+// ReloadXML replaces the engine's data with the devices of a wurfl.xml
+// document and its patches. See Reload.
+func (e *Engine) ReloadXML(r io.Reader, opts *LoadOptions, patches ...io.Reader) error {
+	return e.Reload(func(staging *Engine) error {
+		return staging.LoadXML(r, opts, patches...)
+	})
+}
+
+// Match returns the device for a user agent. When the handler chain
+// picks a device that is not loaded, Match falls back to the closest
+// device that is, see MatchE. It only returns nil when not even the
+// generic device is loaded.
+func (e *Engine) Match(ua string) *Device {
+	dev, _ := e.MatchE(ua)
+	return dev
+}
+
+// MatchE is Match reporting how the device was found. When the id picked
+// by the handler chain is not in the repository it tries, in order:
+//
+//   - the id with trailing "_" parts removed, down to two parts, e.g.
+//     generic_android_ver4 and generic_android for generic_android_ver4_1
+//   - the catch-all id for the user agent's browser family, see
+//     Util.GetMobileCatchAllId
+//   - generic_mobile for mobile browsers, generic_web_browser otherwise
+//   - generic
+//
+// and returns the first one loaded together with a *FallbackError. It
+// returns ErrNoDevice if none of them is loaded.
+func (e *Engine) MatchE(ua string) (*Device, error) {
+	ds := e.current()
+	ds.rlock()
+	defer ds.mu.RUnlock()
+	if dev, err, found := e.cache.get(ds, ua); found {
+		return dev, err
+	}
+	dev, err := ds.resolve(e.util, ua, ds.chain.Match(ua))
+	e.cache.add(ds, ua, dev, err)
+	return dev, err
+}
+
+func (e *Engine) Find(id string) *Device {
+	ds := e.current()
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	return ds.repo.find(id)
+}
+
+// resolve finds the device for the id the chain returned for ua, walking
+// the fallback ladder described at MatchE.
+func (ds *dataset) resolve(u *Util, ua, id string) (*Device, error) {
+	if dev := ds.repo.find(id); dev != nil {
+		return dev, nil
+	}
+	ids := []string{}
+	// Trim down to two parts, generic_android but not generic.
+	for family := id; strings.Count(family, "_") > 1; {
+		family = family[:strings.LastIndex(family, "_")]
+		ids = append(ids, family)
+	}
+	if catchAll := u.GetMobileCatchAllId(ua); catchAll != NO_MATCH {
+		ids = append(ids, catchAll)
+	}
+	if u.IsMobileBrowser(ua) {
+		ids = append(ids, GENERIC_MOBILE)
+	} else {
+		ids = append(ids, GENERIC_WEB_BROWSER)
+	}
+	ids = append(ids, GENERIC)
+	for _, fallback := range ids {
+		if dev := ds.repo.find(fallback); dev != nil {
+			return dev, &FallbackError{UserAgent: ua, Requested: id, Used: fallback}
+		}
+	}
+	return nil, ErrNoDevice
+}
+
+func (ds *dataset) register(id, ua string, actualDeviceRoot bool, capabilities map[string]interface{}, parent string, schema CapabilitySchema) error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	capabilities = ds.repo.typedCapabilities(capabilities, schema)
+	if err := ds.repo.register(id, ua, actualDeviceRoot, capabilities, parent); err != nil {
+		return err
+	}
+	ds.chain.Filter(ua, id)
+	ds.frozen = false
+	ds.gen++
+	return nil
+}
+
+// capabilityTypes returns the types of the registered capabilities,
+// overridden by schema.
+func (ds *dataset) capabilityTypes(schema CapabilitySchema) CapabilitySchema {
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	types := make(CapabilitySchema, len(ds.repo.types)+len(schema))
+	for name, t := range ds.repo.types {
+		types[name] = t
+	}
+	for name, t := range schema {
+		types[name] = t
+	}
+	return types
+}
+
+func (ds *dataset) freeze() {
+	ds.rlock()
+	ds.mu.RUnlock()
+}
+
+// rlock takes the shared lock, freezing the chain first if devices have
+// been registered since the last match.
+func (ds *dataset) rlock() {
+	for {
+		ds.mu.RLock()
+		if ds.frozen {
+			return
+		}
+		ds.mu.RUnlock()
+		ds.mu.Lock()
+		if !ds.frozen {
+			ds.chain.Freeze()
+			ds.uaprof = ds.repo.indexUAProf()
+			ds.frozen = true
+		}
+		ds.mu.Unlock()
+	}
+}
@@ -6,8 +6,6 @@ import (
 		//"fmt"
 		)
 
-var util = new(Util)
-
 type UANormalizer struct{
 	Regexp string 
 	wordRx *regexp.Regexp
@@ -50,7 +48,7 @@ func (blackBerry *BlackBerry) Normalize(ua string) string{
 }
 
 type LocaleRemover struct{
-
+	util *Util
 }
 
 func NewLocaleRemover() *LocaleRemover{
@@ -59,8 +57,12 @@ func NewLocaleRemover() *LocaleRemover{
 
 
 
+func (lr *LocaleRemover) SetUtil(u *Util) {
+	lr.util = u
+}
+
 func (lr *LocaleRemover) Normalize(ua string) string {
-	return util.RemoveLocale(ua)
+	return lr.util.RemoveLocale(ua)
 }
 
 type NovarraGoogleTranslator struct{
@@ -1,16 +1,20 @@
 package wurflgo
 
 import (
+	"reflect"
 	"regexp"
 	"strings"
 	"sort"
 	"strconv"
 	"math"
 	//"fmt"
+
+	"github.com/srinathgs/wurflgo/matcher"
 )
 
 type Handlers interface{
 	SetNextHandler(Handlers)
+	SetUtil(*Util)
 	CanHandle(string) bool
 	Filter(string,string)
 	Match(string)string
@@ -24,22 +28,31 @@ type Handlers interface{
 	GetDeviceIdFromLD(string,int)string
 	IsBlankOrGeneric(string)bool
 	GetOrderedUAS()[]string
+	GetUASWithDeviceId()map[string]string
+	GetNormalizer()Normalizer
+	SetOrderedUAS([]string)
 }
 
-
-
-
 type Chain struct{
 	Handlers []Handlers
+	util *Util
 }
 
+// NewChain creates an empty chain with the keyword lists of NewUtil.
 func NewChain() *Chain{
+	return NewChainWithUtil(NewUtil())
+}
+
+// NewChainWithUtil creates an empty chain whose handlers all share u.
+func NewChainWithUtil(u *Util) *Chain{
 	c := new(Chain)
 	c.Handlers = []Handlers{}
+	c.util = u
 	return c
 }
 
 func (c *Chain) AddHandler(hlr Handlers) *Chain{
+	hlr.SetUtil(c.util)
 	sz := len(c.Handlers)
 	if sz > 0 {
 		c.Handlers[sz - 1].SetNextHandler(hlr)
@@ -48,175 +61,114 @@ func (c *Chain) AddHandler(hlr Handlers) *Chain{
 	return c
 }
 
+// relink points every handler of the chain to the one after it, after
+// the handler list has been changed.
+func (c *Chain) relink() {
+	for i, h := range c.Handlers{
+		h.SetUtil(c.util)
+		if i+1 < len(c.Handlers){
+			h.SetNextHandler(c.Handlers[i+1])
+		}else{
+			h.SetNextHandler(nil)
+		}
+	}
+}
+
 func (c *Chain) Filter(ua string, deviceId string) {
-	util.Reset()
 	c.Handlers[0].Filter(ua,deviceId)
 }
 
 func (c *Chain) Match(ua string) string{
-	util.Reset()
 	return c.Handlers[0].Match(ua)
 }
 
-
-type AlcatelHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-
-func NewAlcatelHandler(norm Normalizer) *AlcatelHandler{
-	alh := new(AlcatelHandler)
-	alh.Normalizer = norm
-	alh.OrderedUAS = []string{}
-	alh.UASWithDeviceId = make(map[string]string)
-	return alh
-}
-
-func (h *AlcatelHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *AlcatelHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
+// HandlerName returns the name a handler is known by in snapshots and
+// match reports, e.g. "AndroidHandler".
+func HandlerName(h Handlers) string{
+	if n, ok := h.(interface{ Name() string }); ok{
+		return n.Name()
 	}
-	if mobile{
-		return GENERIC_MOBILE
+	t := reflect.TypeOf(h)
+	if t.Kind() == reflect.Ptr{
+		t = t.Elem()
 	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
+	return t.Name()
 }
 
-func (h *AlcatelHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
+// Handler returns the handler of the chain with the given name, or nil.
+func (c *Chain) Handler(name string) Handlers{
+	for _, h := range c.Handlers{
+		if HandlerName(h) == name{
+			return h
 		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *AlcatelHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *AlcatelHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *AlcatelHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		//fmt.Println("Alcatel Can Handle")
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
 	}
-	return GENERIC
+	return nil
 }
 
-func (h *AlcatelHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
+// Handlers sort their UA tables lazily, on the first match after a
+// Filter. Freeze builds every table up front so that Match only reads
+// shared state afterwards and can run from many goroutines at once.
+func (c *Chain) Freeze() {
+	for _, h := range c.Handlers{
+		h.GetOrderedUAS()
+		if f, ok := h.(freezer); ok{
+			f.freeze()
+		}
 	}
-	return NO_MATCH
 }
 
-func (h *AlcatelHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
+// freezer is implemented by handlers that keep UA tables besides the one
+// returned by GetOrderedUAS.
+type freezer interface{
+	freeze()
 }
 
-func (h *AlcatelHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *AlcatelHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
+type AlcatelHandler struct{
+	BaseHandler
 }
 
-func (h *AlcatelHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
+func NewAlcatelHandler(norm Normalizer) *AlcatelHandler{
+	alh := new(AlcatelHandler)
+	alh.Init(alh, norm)
+	return alh
 }
 
 func (alh *AlcatelHandler) CanHandle(ua string) bool{
-	if util.IsDesktopBrowser(ua){
+	if alh.util.IsDesktopBrowser(ua){
 		return false
 	}
-	return util.CheckIfStartsWith(ua,"Alcatel") || util.CheckIfStartsWith(ua,"ALCATEL")
-}
-
-func (alh *AlcatelHandler) SetNextHandler(hlr Handlers){
-	alh.nextHandler = hlr
-}
-
-func (h *AlcatelHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
+	return alh.util.CheckIfStartsWith(ua,"Alcatel") || alh.util.CheckIfStartsWith(ua,"ALCATEL")
 }
 
 type AndroidHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+	BaseHandler
 	ConstantIds []string
 	DefaultAndroidVersion string
 	ValidAndroidVersions []string
 	AndroidReleaseMap map[string]string
 	DefaultOperaVersion string
 	ValidOperaVersions []string
-}
+	// releaseRx finds the names of AndroidReleaseMap, compiled by
+	// NewAndroidHandler.
+	releaseRx *regexp.Regexp
+}
+
+var (
+	androidModelRx = regexp.MustCompile(`Android [^;]+; xx-xx; (.+?) Build/`)
+	htcSeparatorRx = regexp.MustCompile(`HTC[ _\-/]`)
+	htcVersionRx = regexp.MustCompile(`(/| V?[\d\.]).*$`)
+	htcSlashRx = regexp.MustCompile(`/.*$`)
+	samsungModelRx = regexp.MustCompile(`(SAMSUNG[^/]+)/.*$`)
+	orangeModelRx = regexp.MustCompile(`ORANGE/.*$`)
+	lgModelRx = regexp.MustCompile(`(LG-[^/]+)/[vV].*$`)
+	serialNumberRx = regexp.MustCompile(`\[[\d]{10}\]`)
+	operaAndroidVersionRx = regexp.MustCompile(`Version\/(\d\d)`)
+	androidVersionRx = regexp.MustCompile(`Android (\d\.\d)`)
+)
 
 func NewAndroidHandler(norm Normalizer) *AndroidHandler{
 	androidHandler := new(AndroidHandler)
+	androidHandler.Init(androidHandler, norm)
 	androidHandler.ConstantIds = []string{
         "generic_android",
         "generic_android_ver1_5",
@@ -286,132 +238,23 @@ func NewAndroidHandler(norm Normalizer) *AndroidHandler{
     }
     androidHandler.DefaultOperaVersion = "10"
     androidHandler.ValidOperaVersions = []string{"10", "11"}
-    androidHandler.Normalizer = norm
-    androidHandler.OrderedUAS = []string{}
-    androidHandler.UASWithDeviceId = make(map[string]string)
+    releases := []string{}
+    for name := range androidHandler.AndroidReleaseMap{
+        releases = append(releases, regexp.QuoteMeta(name))
+    }
+    androidHandler.releaseRx = regexp.MustCompile(strings.Join(releases, "|"))
     return androidHandler
 }
 
-func (h *AndroidHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *AndroidHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *AndroidHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *AndroidHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *AndroidHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
+func (ah *AndroidHandler) FixedIds() []string{
+	return ah.ConstantIds
 }
 
 func (ah *AndroidHandler) CanHandle(ua string) bool{
-	if util.IsDesktopBrowser(ua){
+	if ah.util.IsDesktopBrowser(ua){
 		return false
 	}
-	return util.CheckIfContains(ua,"Android")
-}
-
-func (h *AndroidHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *AndroidHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *AndroidHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *AndroidHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-
-
-func (h *AndroidHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *AndroidHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		//fmt.Println("Android Can Handle")
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (ah *AndroidHandler) SetNextHandler(hlr Handlers){
-	ah.nextHandler = hlr
+	return ah.util.CheckIfContains(ua,"Android")
 }
 
 func (ah *AndroidHandler) ApplyConclusiveMatch(ua string) string{
@@ -422,9 +265,9 @@ func (ah *AndroidHandler) ApplyConclusiveMatch(ua string) string{
 		return ah.GetDeviceIdFromRIS(ua,tolerance)
 	}
 
-	if util.CheckIfContains(ua,"Opera Mini"){
-		if util.CheckIfContains(ua,"Build/"){
-			tolerance = util.IndexOfOrLength(ua,"Build/",0)
+	if ah.util.CheckIfContains(ua,"Opera Mini"){
+		if ah.util.CheckIfContains(ua,"Build/"){
+			tolerance = ah.util.IndexOfOrLength(ua,"Build/",0)
 			return ah.GetDeviceIdFromRIS(ua,tolerance)
 		}
 		prefixes := map[string]string{
@@ -433,20 +276,20 @@ func (ah *AndroidHandler) ApplyConclusiveMatch(ua string) string{
 			"Opera/9.80 (Android; Opera Mini/5.1" : "uabait_opera_mini_android_v51",
 		}
 		for prefix := range prefixes{
-			if util.CheckIfStartsWith(ua,prefix){
+			if ah.util.CheckIfStartsWith(ua,prefix){
 				return ah.GetDeviceIdFromRIS(ua, len(prefix))
 			}
 		}
 	}
-	if util.CheckIfContains(ua, "Opera Mini"){
-		tolerance = util.SecondSlash(ua)
+	if ah.util.CheckIfContains(ua, "Opera Mini"){
+		tolerance = ah.util.SecondSlash(ua)
 		return ah.GetDeviceIdFromRIS(ua,tolerance)
 	}
-	if util.CheckIfContainsAnyOf(ua, []string{"Fennec","Firefox"}){
-		tolerance = util.IndexOfOrLength(ua,")",0)
+	if ah.util.CheckIfContainsAnyOf(ua, []string{"Fennec","Firefox"}){
+		tolerance = ah.util.IndexOfOrLength(ua,")",0)
 		return ah.GetDeviceIdFromRIS(ua,tolerance)
 	}
-	if util.CheckIfContains(ua,"UCWEB7"){
+	if ah.util.CheckIfContains(ua,"UCWEB7"){
 		strToFind := "UCWEB7"
 		fndIdx := strings.Index(ua,strToFind)
 		if fndIdx != -1{
@@ -458,7 +301,7 @@ func (ah *AndroidHandler) ApplyConclusiveMatch(ua string) string{
 		}
 		return ah.GetDeviceIdFromRIS(ua,tolerance)
 	}
-	if util.CheckIfContains(ua,"UCWEB7"){
+	if ah.util.CheckIfContains(ua,"UCWEB7"){
 		strToFind := "UCWEB7"
 		fndIdx := strings.Index(ua,strToFind)
 		if fndIdx != -1{
@@ -470,7 +313,7 @@ func (ah *AndroidHandler) ApplyConclusiveMatch(ua string) string{
 		}
 		return ah.GetDeviceIdFromRIS(ua,tolerance)
 	}
-	if util.CheckIfContains(ua,"NetFrontLifeBrowser/2.2"){
+	if ah.util.CheckIfContains(ua,"NetFrontLifeBrowser/2.2"){
 		strToFind := "NetFrontLifeBrowser/2.2"
 		fndIdx := strings.Index(ua,strToFind)
 		if fndIdx != -1{
@@ -482,8 +325,8 @@ func (ah *AndroidHandler) ApplyConclusiveMatch(ua string) string{
 		}
 		return ah.GetDeviceIdFromRIS(ua,tolerance)
 	}
-	buildL := util.IndexOfOrLength(ua,"Build/",0)
-	appleL := util.IndexOfOrLength(ua,"AppleWebKit",0)
+	buildL := ah.util.IndexOfOrLength(ua,"Build/",0)
+	appleL := ah.util.IndexOfOrLength(ua,"AppleWebKit",0)
 	if buildL < appleL{
 		tolerance = buildL
 	} else {
@@ -493,33 +336,31 @@ func (ah *AndroidHandler) ApplyConclusiveMatch(ua string) string{
 }
 
 func (ah *AndroidHandler) GetAndroidModel(ua string) string {
-	wordRx := regexp.MustCompile(`Android [^;]+; xx-xx; (.+?) Build/`)
-	matches := wordRx.FindStringSubmatch(ua)
+	matches := androidModelRx.FindStringSubmatch(ua)
 	if len(matches) == 0{
 		return NO_MATCH
 	}
-	model := strings.TrimRight(matches[1]," ;")
+	return cleanAndroidModel(matches[1])
+}
+
+// cleanAndroidModel strips the versions, separators and serial numbers
+// some vendors put in the model of an Android user agent.
+func cleanAndroidModel(model string) string {
+	model = strings.TrimRight(model," ;")
 
 	if strings.Index(model, "Build/") == 0{
 		return NO_MATCH
 	}
 	if strings.Index(model,"HTC") != -1{
-		htcRx := regexp.MustCompile(`HTC[ _\-/]`)
-		model = htcRx.ReplaceAllString(model, "HTC~")
-		remVer := regexp.MustCompile(`(/| V?[\d\.]).*$`)
-		model = remVer.ReplaceAllString(model,"")
-		remDot := regexp.MustCompile(`/.*$`)
-		model = remDot.ReplaceAllString(model,"")
-	}
-	samsungRx := regexp.MustCompile(`(SAMSUNG[^/]+)/.*$`)
-	orangeRx := regexp.MustCompile(`ORANGE/.*$`)
-	lgRx := regexp.MustCompile(`(LG-[^/]+)/[vV].*$`)
-	serNoRx := regexp.MustCompile(`\[[\d]{10}\]`)
-
-	model = samsungRx.ReplaceAllString(model,`\1`)
-	model = orangeRx.ReplaceAllString(model,`ORANGE`)
-	model = lgRx.ReplaceAllString(model,`\1`)
-	model = serNoRx.ReplaceAllString(model,"")
+		model = htcSeparatorRx.ReplaceAllString(model, "HTC~")
+		model = htcVersionRx.ReplaceAllString(model,"")
+		model = htcSlashRx.ReplaceAllString(model,"")
+	}
+
+	model = samsungModelRx.ReplaceAllString(model,`${1}`)
+	model = orangeModelRx.ReplaceAllString(model,`ORANGE`)
+	model = lgModelRx.ReplaceAllString(model,`${1}`)
+	model = serialNumberRx.ReplaceAllString(model,"")
 
 	return strings.Trim(model," ")
 
@@ -529,8 +370,7 @@ func (ah *AndroidHandler) GetOperaOnAndroidVersion(ua string, useDefault bool) s
 	if useDefault == true{
 		return ah.DefaultOperaVersion
 	}
-	wordRx := regexp.MustCompile(`Version\/(\d\d)`)
-	matches := wordRx.FindStringSubmatch(ua)
+	matches := operaAndroidVersionRx.FindStringSubmatch(ua)
 	if len(matches) == 0{
 		return NO_MATCH
 	}
@@ -548,17 +388,10 @@ func (ah *AndroidHandler) GetAndroidVersion(ua string, useDefault bool) string{
 	if useDefault == true{
 		return ah.DefaultAndroidVersion
 	}
-	keys := []string{}
-	for k,_ := range ah.AndroidReleaseMap{
-		keys = append(keys,k)
-	}
-	pattern := strings.Join(keys,"|")
-	wordRx := regexp.MustCompile(pattern)
-	ua = wordRx.ReplaceAllStringFunc(ua, func(match string) string{
+	ua = ah.releaseRx.ReplaceAllStringFunc(ua, func(match string) string{
 		return ah.AndroidReleaseMap[match]
 	})
-	verRx := regexp.MustCompile(`Android (\d\.\d)`)
-	matches := verRx.FindStringSubmatch(ua)
+	matches := androidVersionRx.FindStringSubmatch(ua)
 	if len(matches) == 0{
 		return NO_MATCH
 	}
@@ -566,159 +399,119 @@ func (ah *AndroidHandler) GetAndroidVersion(ua string, useDefault bool) string{
 	return version
 }
 
-
 type AppleHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	ConstantIds []string
+	BaseHandler
+	// ConstantIds are the devices ApplyRecoveryMatch picks by family when
+	// the table has no id for the iOS version.
+	ConstantIds map[string]string
+	// versionIds lists the apple_*_ver* ids of the table by family, see
+	// getVersionIds.
+	versionIds map[string][]appleVersionId
 }
 
-func NewAppleHandler(norm Normalizer) *AppleHandler{
-	aph := new(AppleHandler)
-	aph.ConstantIds = []string{
-		"apple_ipod_touch_ver1",
-        "apple_ipod_touch_ver2",
-        "apple_ipod_touch_ver3",
-        "apple_ipod_touch_ver4",
-        "apple_ipod_touch_ver5",
-
-        "apple_ipad_ver1",
-        "apple_ipad_ver1_sub42",
-        "apple_ipad_ver1_sub5",
-
-        "apple_iphone_ver1",
-        "apple_iphone_ver2",
-        "apple_iphone_ver3",
-        "apple_iphone_ver4",
-        "apple_iphone_ver5",
-	}
-	aph.Normalizer = norm
-	aph.OrderedUAS = []string{}
-	aph.UASWithDeviceId = make(map[string]string)
-	return aph
+// appleVersionId is a device id for a family of Apple devices running a
+// given iOS version or later.
+type appleVersionId struct{
+	major, minor int
+	id string
 }
 
-func (h *AppleHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
+// The families of versioned Apple ids, e.g. apple_iphone_ver17_1 or
+// apple_ipad_ver1_sub12.
+const (
+	appleIPhoneFamily = "apple_iphone_ver"
+	appleIPadFamily = "apple_ipad_ver1_sub"
+	appleIPodFamily = "apple_ipod_touch_ver"
+)
 
-func (h *AppleHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
+var (
+	appleVersionIdRx = regexp.MustCompile(`^(apple_iphone_ver|apple_ipad_ver1_sub|apple_ipod_touch_ver)(\d+)(?:_(\d+))?$`)
+	// appleIPadMinorIds are the first iPad ids, which write the minor
+	// version without a separator. They read like the ids of iOS 10 and
+	// later, such as apple_ipad_ver1_sub12, so they are listed with their
+	// major and minor version.
+	appleIPadMinorIds = map[string][2]int{
+		"apple_ipad_ver1_sub42": {4, 2},
+		"apple_ipad_ver1_sub43": {4, 3},
+		"apple_ipad_ver1_sub51": {5, 1},
+	}
+	iosVersionRx = regexp.MustCompile(` (\d+)_(\d+)[ _]`)
+	iosSafariVersionRx = regexp.MustCompile(`Version/(\d+)\.(\d+)`)
+	// iPadOS 13 and later ask for desktop sites with the user agent of
+	// Safari on macOS, whose version is frozen at 10_15.
+	iPadDesktopRx = regexp.MustCompile(`^Mozilla/5\.0 \(Macintosh; Intel Mac OS X 10_15(?:_\d+)?\) AppleWebKit/`)
+)
 
-func(h *AppleHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *AppleHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+func NewAppleHandler(norm Normalizer) *AppleHandler{
+	aph := new(AppleHandler)
+	aph.Init(aph, norm)
+	aph.ConstantIds = map[string]string{
+		appleIPodFamily: "apple_ipod_touch_ver1",
+		appleIPadFamily: "apple_ipad_ver1",
+		appleIPhoneFamily: "apple_iphone_ver1",
 	}
-	return NO_MATCH
-}
-
-func (h *AppleHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
+	return aph
 }
 
-
-func (h *AppleHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
+func (aph *AppleHandler) FixedIds() []string{
+	ids := make([]string, 0, len(aph.ConstantIds))
+	for _, id := range aph.ConstantIds{
+		ids = append(ids, id)
+	}
+	return ids
 }
 
 func (aph *AppleHandler) CanHandle(ua string) bool{
-	if util.IsDesktopBrowser(ua){
-		return false
+	if aph.IsIPadDesktopMode(ua){
+		return true
 	}
-	return util.CheckIfStartsWith(ua,"Mozilla/5") && util.CheckIfContainsAnyOf(ua,[]string{"iPhone","iPad","iPod"})
-}
-
-func (h *AppleHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
+	if aph.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return deviceId
+	return aph.util.CheckIfStartsWith(ua,"Mozilla/5") && aph.util.CheckIfContainsAnyOf(ua,[]string{"iPhone","iPad","iPod"})
 }
 
-func (h *AppleHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
+// IsIPadDesktopMode reports the user agents of iPads asking for desktop
+// sites. They are those of Safari on macOS, so only the ones giving
+// themselves away are recognized: with the Mobile/ token of iOS web
+// views, or the token of a known app. The plain user agent Safari itself
+// sends in desktop mode is not: neither it nor the other headers Safari
+// sends, which include no client hints, tell an iPad from a Mac.
+func (aph *AppleHandler) IsIPadDesktopMode(ua string) bool{
+	if !iPadDesktopRx.MatchString(ua){
+		return false
 	}
-	return NO_MATCH
-}
-
-func (aph *AppleHandler) SetNextHandler(hlr Handlers){
-	aph.nextHandler = hlr
+	return aph.util.CheckIfContains(ua," Mobile/") || aph.util.IsApp(ua)
 }
 
-func (h *AppleHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		//fmt.Println("Apple Can Handle")
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
+// IsWebView reports the user agents of web views in iOS apps, which send
+// the token of a known app, or leave out the Safari/ token Mobile Safari
+// and the other browsers send.
+func (aph *AppleHandler) IsWebView(ua string) bool{
+	if aph.util.IsApp(ua){
+		return true
 	}
-	return GENERIC
+	return aph.util.CheckIfContains(ua," Mobile/") && !aph.util.CheckIfContains(ua,"Safari/")
 }
 
-func (h *AppleHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
+// IOSVersion returns the major and minor iOS version of ua, or -1 and -1.
+// iPads in desktop mode only tell the version of Safari, which follows
+// the iOS version since iOS 13.
+func (aph *AppleHandler) IOSVersion(ua string) (int, int){
+	rx := iosVersionRx
+	if aph.IsIPadDesktopMode(ua){
+		rx = iosSafariVersionRx
 	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
+	matches := rx.FindStringSubmatch(ua)
+	if len(matches) == 0{
+		return -1, -1
 	}
-	return
+	major, _ := strconv.Atoi(matches[1])
+	minor, _ := strconv.Atoi(matches[2])
+	return major, minor
 }
 
-func (aph *AppleHandler) ApplyConclusiveMatch(ua string) string{
+func (aph *AppleHandler) Tolerance(ua string) int{
 	tolerance := strings.Index(ua,"_")
 	if tolerance != -1 {
 		tolerance += 1
@@ -730,203 +523,117 @@ func (aph *AppleHandler) ApplyConclusiveMatch(ua string) string{
 			tolerance = len(ua)
 		}
 	}
-	return aph.GetDeviceIdFromRIS(ua, tolerance)
+	return tolerance
 }
 
+// ApplyRecoveryMatch picks the device of the family of ua for the newest
+// iOS version up to the one of ua, among the apple_*_ver* ids of the
+// table, or the constant id of the family.
 func (aph *AppleHandler) ApplyRecoveryMatch(ua string) string{
-	wordRx := regexp.MustCompile(` (\d)_(\d)[ _]`)
-	matches := wordRx.FindStringSubmatch(ua)
-	var MajorVersion int
-	var MinorVersion int
-	if len(matches) > 0{
-		MajorVersion, _ = strconv.Atoi(matches[1])
-		MinorVersion, _ = strconv.Atoi(matches[2])
-	} else {
-		MajorVersion = -1
-		MinorVersion = -1
-	}
-	
-	MinorVersion = MinorVersion
-	if util.CheckIfContains(ua, "iPod"){
-		deviceId := "apple_ipod_touch_ver" + strconv.Itoa(MajorVersion)
-		if util.CheckIfContainsAnyOf(deviceId,aph.ConstantIds){
-			return deviceId
-		} else {
-			return "apple_ipod_touch_ver1"
-		}
-	} else if util.CheckIfContains(ua, "iPad") {
-		if MajorVersion == 5{
-			return "apple_ipad_ver1_sub5"
-		} else if MajorVersion == 4 {
-			return "apple_ipad_ver1_sub42"
-		} else {
-			return "apple_ipad_ver1"
-		}
-	} else if util.CheckIfContains(ua, "iPhone"){
-		deviceId := "apple_iphone_touch_ver" + strconv.Itoa(MajorVersion)
-		if util.CheckIfContainsAnyOf(deviceId,aph.ConstantIds){
-			return deviceId
-		} else {
-			return "apple_iphone_ver1"
-		}
+	major, minor := aph.IOSVersion(ua)
+	switch {
+	case aph.util.CheckIfContains(ua, "iPod"):
+		return aph.versionId(appleIPodFamily, major, minor)
+	case aph.util.CheckIfContains(ua, "iPad") || aph.IsIPadDesktopMode(ua):
+		return aph.versionId(appleIPadFamily, major, minor)
+	case aph.util.CheckIfContains(ua, "iPhone"):
+		return aph.versionId(appleIPhoneFamily, major, minor)
 	}
 	return NO_MATCH
 }
 
-type BenQHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-
-func NewBenQHandler(norm Normalizer) *BenQHandler{
-	bh := new(BenQHandler)
-	bh.Normalizer = norm
-	bh.OrderedUAS = []string{}
-	bh.UASWithDeviceId = make(map[string]string)
-	return bh
-}
-
-func (h *BenQHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
+// versionId returns the id of family for the newest version up to
+// major.minor, or the constant id of family.
+func (aph *AppleHandler) versionId(family string, major, minor int) string{
+	deviceId := aph.ConstantIds[family]
+	if major < 0{
+		return deviceId
+	}
+	for _, v := range aph.getVersionIds()[family]{
+		if v.major > major || (v.major == major && v.minor > minor){
+			break
 		}
+		deviceId = v.id
 	}
 	return deviceId
 }
 
-func (h *BenQHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *BenQHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
+// getVersionIds returns the apple_*_ver* ids of the table by family,
+// oldest version first, built on the first call after a Filter. The ids
+// of appleIPadMinorIds have the version they list.
+func (aph *AppleHandler) getVersionIds() map[string][]appleVersionId{
+	if aph.versionIds == nil{
+		versionIds := map[string][]appleVersionId{}
+		seen := map[string]bool{}
+		for _, deviceId := range aph.UASWithDeviceId{
+			if seen[deviceId]{
+				continue
+			}
+			seen[deviceId] = true
+			matches := appleVersionIdRx.FindStringSubmatch(deviceId)
+			if len(matches) == 0{
+				continue
+			}
+			v := appleVersionId{id: deviceId}
+			if version, found := appleIPadMinorIds[deviceId]; found{
+				v.major, v.minor = version[0], version[1]
+			} else {
+				v.major, _ = strconv.Atoi(matches[2])
+				if matches[3] != ""{
+					v.minor, _ = strconv.Atoi(matches[3])
+				}
+			}
+			versionIds[matches[1]] = append(versionIds[matches[1]], v)
 		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *BenQHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
+		for _, ids := range versionIds{
+			sort.Slice(ids, func(i, j int) bool{
+				if ids[i].major != ids[j].major{
+					return ids[i].major < ids[j].major
+				}
+				return ids[i].minor < ids[j].minor
+			})
 		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *BenQHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *BenQHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+		aph.versionIds = versionIds
 	}
-	return NO_MATCH
-}
-
-func (h *BenQHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
+	return aph.versionIds
 }
 
-func (b *BenQHandler) SetNextHandler(hlr Handlers){
-	b.nextHandler = hlr
-}
-
-func (h *BenQHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
+func (aph *AppleHandler) Filter(ua string, deviceId string) {
+	if aph.CanHandle(ua){
+		aph.versionIds = nil
 	}
-	return NO_MATCH
+	aph.BaseHandler.Filter(ua, deviceId)
 }
 
-func (h *BenQHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
+func (aph *AppleHandler) freeze() {
+	aph.getVersionIds()
 }
 
-func (h *BenQHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
+type BenQHandler struct{
+	BaseHandler
 }
 
-func (h *BenQHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		//fmt.Println("BenQ Can Handle")
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
+func NewBenQHandler(norm Normalizer) *BenQHandler{
+	bh := new(BenQHandler)
+	bh.Init(bh, norm)
+	return bh
 }
 
 func (b *BenQHandler) CanHandle(ua string) bool{
-	if util.IsDesktopBrowser(ua){
+	if b.util.IsDesktopBrowser(ua){
 		return false
 	}
-	return util.CheckIfStartsWith(ua,"BenQ") || util.CheckIfStartsWith(ua,"BENQ")
-}
-
-func (h *BenQHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
+	return b.util.CheckIfStartsWith(ua,"BenQ") || b.util.CheckIfStartsWith(ua,"BENQ")
 }
 
-
 type BlackBerryHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+	BaseHandler
 	ConstantIds map[string]string
 }
 
 func NewBlackBerryHandler(norm Normalizer) *BlackBerryHandler {
 	blh := new(BlackBerryHandler)
+	blh.Init(blh, norm)
 	blh.ConstantIds = map[string]string{
 		"2.": "blackberry_generic_ver2",
         "3.2": "blackberry_generic_ver3_sub2",
@@ -944,166 +651,66 @@ func NewBlackBerryHandler(norm Normalizer) *BlackBerryHandler {
         "5.": "blackberry_generic_ver5",
         "6.": "blackberry_generic_ver6",
 	}
-	blh.Normalizer = norm
-	blh.UASWithDeviceId = make(map[string]string)
-	blh.OrderedUAS = []string{}
 	return blh
 }
 
-func (h *BlackBerryHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
+func (blh *BlackBerryHandler) FixedIds() []string{
+	ids := make([]string, 0, len(blh.ConstantIds))
+	for _, id := range blh.ConstantIds{
+		ids = append(ids, id)
 	}
-	return deviceId
+	return ids
 }
 
-func (h *BlackBerryHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
+func (blh *BlackBerryHandler) CanHandle(ua string) bool {
+	if blh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return GENERIC
+	return blh.util.CheckIfContainsCaseInsensitive(ua,"BlackBerry")
 }
 
-func (h *BlackBerryHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
+func (blh *BlackBerryHandler) Tolerance(ua string) int{
+	var tolerance int
+	if blh.util.CheckIfStartsWith(ua,"Mozilla/4"){
+		tolerance = blh.util.SecondSlash(ua)
+	} else if blh.util.CheckIfStartsWith(ua,"Mozilla/5"){
+		tolerance = blh.util.OrdinalIndexOf(ua,";",3)
+	} else {
+		tolerance = blh.util.FirstSlash(ua)
 	}
-	return h.OrderedUAS
+	return tolerance
 }
 
-func(h *BlackBerryHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+var blackBerryVersionRx = regexp.MustCompile(`BlackBerry[^/\s]+/(\d.\d)`)
+
+func (blh *BlackBerryHandler) ApplyRecoveryMatch(ua string) string {
+	matches := blackBerryVersionRx.FindStringSubmatch(ua)
+	if len(matches) > 0{
+		// The longest version code the version starts with, so that 4.2
+		// is preferred to 4. whatever the order of the map.
+		version, best := matches[1], ""
+		for verCode := range blh.ConstantIds{
+			if len(verCode) > len(best) && strings.HasPrefix(version, verCode){
+				best = verCode
+			}
+		}
+		if best != ""{
+			return blh.ConstantIds[best]
+		}
 	}
 	return NO_MATCH
 }
-func(h *BlackBerryHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (blh *BlackBerryHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfContainsCaseInsensitive(ua,"BlackBerry")
-}
-
-
-func (h *BlackBerryHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *BlackBerryHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *BlackBerryHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *BlackBerryHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (blh *BlackBerryHandler) SetNextHandler(hlr Handlers){
-	blh.nextHandler = hlr
-}
-
-func (h *BlackBerryHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (blh *BlackBerryHandler) ApplyConclusiveMatch(ua string) string{
-	var tolerance int
-	if util.CheckIfStartsWith(ua,"Mozilla/4"){
-		tolerance = util.SecondSlash(ua)
-	} else if util.CheckIfStartsWith(ua,"Mozilla/5"){
-		tolerance = util.OrdinalIndexOf(ua,";",3)
-	} else {
-		tolerance = util.FirstSlash(ua)
-	}
-	return blh.GetDeviceIdFromRIS(ua, tolerance)
-}
-
-func (blh *BlackBerryHandler) ApplyRecoveryMatch(ua string) string {
-	wordRx := regexp.MustCompile(`BlackBerry[^/\s]+/(\d.\d)`)
-	matches := wordRx.FindStringSubmatch(ua)
-	if len(matches) > 0{
-		version := matches[1]
-		for verCode, deviceId := range blh.ConstantIds{
-			if strings.Index(version, verCode) != -1{
-				return deviceId
-			}
-		} 
-	}
-	return NO_MATCH
-}
-
 
 type BotCrawlerTranscoderHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+	BaseHandler
 	botCrawlerTrancoder []string
+	// botKeywords finds the lower-cased botCrawlerTrancoder keywords.
+	botKeywords *matcher.AhoCorasick
 }
 
 func NewBotCrawlerTranscoderHandler(norm Normalizer) *BotCrawlerTranscoderHandler {
 	bth := new(BotCrawlerTranscoderHandler)
+	bth.Init(bth, norm)
 	bth.botCrawlerTrancoder = []string{
 		"bot",
         "crawler",
@@ -1147,146 +754,20 @@ func NewBotCrawlerTranscoderHandler(norm Normalizer) *BotCrawlerTranscoderHandle
         "hatena",
         "ichiro",
 	}
-	bth.Normalizer = norm
-	bth.OrderedUAS = []string{}
-	bth.UASWithDeviceId = make(map[string]string)
-	return bth
-}
-
-func (h *BotCrawlerTranscoderHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *BotCrawlerTranscoderHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *BotCrawlerTranscoderHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *BotCrawlerTranscoderHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *BotCrawlerTranscoderHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *BotCrawlerTranscoderHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (bth *BotCrawlerTranscoderHandler) CanHandle(ua string) bool {
+	keywords := make([]string, len(bth.botCrawlerTrancoder))
 	for i := range bth.botCrawlerTrancoder{
-		if util.CheckIfContainsCaseInsensitive(ua, bth.botCrawlerTrancoder[i]){
-			return true
-		}
-	}
-	return false
-}
-
-func (h *BotCrawlerTranscoderHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *BotCrawlerTranscoderHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *BotCrawlerTranscoderHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *BotCrawlerTranscoderHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *BotCrawlerTranscoderHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *BotCrawlerTranscoderHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
+		keywords[i] = strings.ToLower(bth.botCrawlerTrancoder[i])
 	}
-	return
+	bth.botKeywords = matcher.NewAhoCorasick(keywords)
+	return bth
 }
 
-func (bth *BotCrawlerTranscoderHandler) SetNextHandler(hlr Handlers){
-	bth.nextHandler = hlr
+func (bth *BotCrawlerTranscoderHandler) CanHandle(ua string) bool {
+	return bth.botKeywords.ContainsAny(strings.ToLower(ua))
 }
 
 type CatchAllHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+	BaseHandler
 	MozillaTolerance int
 	Mozilla5 string
 	Mozilla4 string
@@ -1294,146 +775,44 @@ type CatchAllHandler struct{
 	Mozilla4OrderedUAS []string
 	Mozilla5UASWithDeviceId  map[string]string
 	Mozilla5OrderedUAS []string
+	// mozilla4Keys and mozilla5Keys find the known Mozilla UAs contained in
+	// a user agent, see getMozilla4Keys.
+	mozilla4Keys *matcher.AhoCorasick
+	mozilla5Keys *matcher.AhoCorasick
 }
 
 func NewCatchAllHandler(norm Normalizer) *CatchAllHandler{
 	cah := new(CatchAllHandler)
+	cah.Init(cah, norm)
 	cah.MozillaTolerance = 5
 	cah.Mozilla5 = "CATCH_ALL_MOZILLA5"
 	cah.Mozilla4 = "CATCH_ALL_MOZILLA4"
-	cah.OrderedUAS = []string{}
-	cah.UASWithDeviceId = make(map[string]string)
 	cah.Mozilla4UASWithDeviceId = map[string]string{}
 	cah.Mozilla4OrderedUAS = []string{}
 	cah.Mozilla5UASWithDeviceId = map[string]string{}
 	cah.Mozilla5OrderedUAS = []string{}
-	cah.Normalizer = norm
 	return cah
 }
 
-func (h *CatchAllHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *CatchAllHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *CatchAllHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *CatchAllHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *CatchAllHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-
 func (cah *CatchAllHandler) CanHandle(ua string) bool {
 	return true
 }
 
-func (h *CatchAllHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *CatchAllHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *CatchAllHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-
-
-func (cah *CatchAllHandler) SetNextHandler(hlr Handlers){
-	cah.nextHandler = hlr
-}
-
-func (h *CatchAllHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
 func (cah *CatchAllHandler) ApplyConclusiveMatch(ua string) string {
 	deviceId := GENERIC
-	if util.CheckIfStartsWith(ua,"Mozilla"){
+	if cah.util.CheckIfStartsWith(ua,"Mozilla"){
 		deviceId = cah.applyMozillaConclusiveMatch(ua)
 	} else {
-		tolerance := util.FirstSlash(ua)
+		tolerance := cah.util.FirstSlash(ua)
 		deviceId = cah.GetDeviceIdFromRIS(ua,tolerance)
 	}
 	return deviceId
 }
 
 func (cah *CatchAllHandler) ApplyExactMatch(ua string) string {
-	for k := range cah.UASWithDeviceId{
-		if ua == k{
-			return cah.UASWithDeviceId[k]
-		}
-	}
-	for k := range cah.Mozilla4UASWithDeviceId{
-		if ua == k{
-			return cah.Mozilla4UASWithDeviceId[k]
-		}
-	}
-	for k := range cah.Mozilla5UASWithDeviceId{
-		if ua == k{
-			return cah.Mozilla5UASWithDeviceId[k]
+	for _, uas := range []map[string]string{cah.UASWithDeviceId, cah.Mozilla4UASWithDeviceId, cah.Mozilla5UASWithDeviceId}{
+		if deviceId, found := uas[ua]; found{
+			return deviceId
 		}
 	}
 	return NO_MATCH
@@ -1446,18 +825,14 @@ func (cah *CatchAllHandler) applyMozillaConclusiveMatch(ua string) string {
 	if cah.isMozilla4(ua){
 		return cah.applyMozilla4ConclusiveMatch(ua)
 	}
-	match := util.LDMatch(cah.GetOrderedUAS(),ua,cah.MozillaTolerance)
+	match := cah.util.LDMatch(cah.GetOrderedUAS(),ua,cah.MozillaTolerance)
 	return cah.UASWithDeviceId[match]
 }
 
 func (cah *CatchAllHandler) applyMozilla5ConclusiveMatch(ua string) string {
-	var keys = []string{}
-	for i := range cah.Mozilla5UASWithDeviceId{
-		keys = append(keys,i)
-	}
 	var match string
-	if !util.CheckIfContainsAnyOf(ua,keys){
-		match = util.LDMatch(cah.getMozilla5OrderedUAS(),ua,cah.MozillaTolerance)
+	if !cah.getMozilla5Keys().ContainsAny(ua){
+		match = cah.util.LDMatch(cah.getMozilla5OrderedUAS(),ua,cah.MozillaTolerance)
 	}
 	if match != ""{
 		return cah.Mozilla5UASWithDeviceId[match]
@@ -1466,13 +841,9 @@ func (cah *CatchAllHandler) applyMozilla5ConclusiveMatch(ua string) string {
 }
 
 func (cah *CatchAllHandler) applyMozilla4ConclusiveMatch(ua string) string {
-	var keys = []string{}
-	for i := range cah.Mozilla4UASWithDeviceId{
-		keys = append(keys,i)
-	}
 	var match string
-	if !util.CheckIfContainsAnyOf(ua,keys){
-		match = util.LDMatch(cah.getMozilla4OrderedUAS(),ua,cah.MozillaTolerance)
+	if !cah.getMozilla4Keys().ContainsAny(ua){
+		match = cah.util.LDMatch(cah.getMozilla4OrderedUAS(),ua,cah.MozillaTolerance)
 	}
 	if match != ""{
 		return cah.Mozilla4UASWithDeviceId[match]
@@ -1484,10 +855,12 @@ func (cah *CatchAllHandler) Filter(ua string, deviceId string) {
 	if cah.isMozilla4(ua){
 		cah.Mozilla4UASWithDeviceId[cah.Normalizer.Normalize(ua)] = deviceId
 		cah.Mozilla4OrderedUAS = []string{}
+		cah.mozilla4Keys = nil
 	}
 	if cah.isMozilla5(ua){
 		cah.Mozilla5UASWithDeviceId[cah.Normalizer.Normalize(ua)] = deviceId
 		cah.Mozilla5OrderedUAS = []string{}
+		cah.mozilla5Keys = nil
 	}
 	if cah.nextHandler != nil{
 		cah.nextHandler.Filter(ua,deviceId)
@@ -1496,19 +869,43 @@ func (cah *CatchAllHandler) Filter(ua string, deviceId string) {
 }
 
 func (cah *CatchAllHandler) isMozilla5(ua string) bool{
-	return util.CheckIfStartsWith(ua,"Mozilla/5")
+	return cah.util.CheckIfStartsWith(ua,"Mozilla/5")
 }
 
 func (cah *CatchAllHandler) isMozilla4(ua string) bool{
-	return util.CheckIfStartsWith(ua,"Mozilla/4")
+	return cah.util.CheckIfStartsWith(ua,"Mozilla/4")
 }
 
 func (cah *CatchAllHandler) isMozilla(ua string) bool{
-	return util.CheckIfStartsWith(ua,"Mozilla")
+	return cah.util.CheckIfStartsWith(ua,"Mozilla")
+}
+
+func (cah *CatchAllHandler) freeze() {
+	cah.getMozilla4OrderedUAS()
+	cah.getMozilla5OrderedUAS()
+	cah.getMozilla4Keys()
+	cah.getMozilla5Keys()
+}
+
+// getMozilla4Keys returns an index of the Mozilla/4 UAs, built on the
+// first call after a Filter, which tells in one pass over a user agent
+// whether it contains any of them.
+func (cah *CatchAllHandler) getMozilla4Keys() *matcher.AhoCorasick {
+	if cah.mozilla4Keys == nil{
+		cah.mozilla4Keys = matcher.NewAhoCorasick(cah.getMozilla4OrderedUAS())
+	}
+	return cah.mozilla4Keys
+}
+
+func (cah *CatchAllHandler) getMozilla5Keys() *matcher.AhoCorasick {
+	if cah.mozilla5Keys == nil{
+		cah.mozilla5Keys = matcher.NewAhoCorasick(cah.getMozilla5OrderedUAS())
+	}
+	return cah.mozilla5Keys
 }
 
 func (cah *CatchAllHandler) getMozilla4OrderedUAS() []string {
-	if len(cah.Mozilla4OrderedUAS) == 0 {
+	if len(cah.Mozilla4OrderedUAS) == 0 && len(cah.Mozilla4UASWithDeviceId) > 0 {
 		cah.Mozilla4OrderedUAS = []string{}
 		for k := range cah.Mozilla4UASWithDeviceId{
 			cah.Mozilla4OrderedUAS = append(cah.Mozilla4OrderedUAS,k)
@@ -1519,7 +916,7 @@ func (cah *CatchAllHandler) getMozilla4OrderedUAS() []string {
 }
 
 func (cah *CatchAllHandler) getMozilla5OrderedUAS() []string {
-	if len(cah.Mozilla5OrderedUAS) == 0 {
+	if len(cah.Mozilla5OrderedUAS) == 0 && len(cah.Mozilla5UASWithDeviceId) > 0 {
 		cah.Mozilla5OrderedUAS = []string{}
 		for k := range cah.Mozilla5UASWithDeviceId{
 			cah.Mozilla5OrderedUAS = append(cah.Mozilla5OrderedUAS,k)
@@ -1529,150 +926,33 @@ func (cah *CatchAllHandler) getMozilla5OrderedUAS() []string {
 	return cah.Mozilla5OrderedUAS
 }
 
-
 type ChromeHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+	BaseHandler
 	ConstantIds []string
 }
 
 func NewChromeHandler(norm Normalizer) *ChromeHandler{
 	ch := new(ChromeHandler)
+	ch.Init(ch, norm)
 	ch.ConstantIds = []string{
 		"google_chrome",
 	}
-	ch.Normalizer = norm
-	ch.OrderedUAS = []string{}
-	ch.UASWithDeviceId = make(map[string]string)
 	return ch
 }
 
-
-func (h *ChromeHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *ChromeHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	//fmt.Println(h.OrderedUAS)
-	return h.OrderedUAS
-}
-
-func(h *ChromeHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *ChromeHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *ChromeHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-
-
-func (h *ChromeHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *ChromeHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (ch *ChromeHandler) SetNextHandler(hlr Handlers){
-	ch.nextHandler = hlr
-}
-
-func (h *ChromeHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *ChromeHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *ChromeHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		//fmt.Println("Chrome Can Handle")
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
+func (ch *ChromeHandler) FixedIds() []string{
+	return ch.ConstantIds
 }
 
 func (ch *ChromeHandler) CanHandle(ua string) bool {
-	if util.IsMobileBrowser(ua){
+	if ch.util.IsMobileBrowser(ua){
 		return false
 	}
-	return util.CheckIfContains(ua,"Chrome")
+	return ch.util.CheckIfContains(ua,"Chrome")
 }
 
-func (ch *ChromeHandler) ApplyConclusiveMatch(ua string) string{
-	tolerance := util.IndexOfOrLength("/",ua,strings.Index(ua,"Chrome"))
-	return ch.GetDeviceIdFromRIS(ua,tolerance)
+func (ch *ChromeHandler) Tolerance(ua string) int{
+	return ch.util.IndexOfOrLength(ua,"/",strings.Index(ua,"Chrome"))
 }
 
 func (ch *ChromeHandler) ApplyRecoveryMatch(ua string) string {
@@ -1680,320 +960,97 @@ func (ch *ChromeHandler) ApplyRecoveryMatch(ua string) string {
 }
 
 type DoCoMoHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+	BaseHandler
 	ConstantIds []string
 }
 
 func NewDoCoMoHandler(norm Normalizer) *DoCoMoHandler{
 	dh := new(DoCoMoHandler)
+	dh.Init(dh, norm)
 	dh.ConstantIds = []string{
 		"docomo_generic_jap_ver1",
         "docomo_generic_jap_ver2",
 	}
-	dh.Normalizer = norm
-	dh.OrderedUAS = []string{}
-	dh.UASWithDeviceId = make(map[string]string)
 	return dh
 }
 
-
-func (h *DoCoMoHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
+func (dh *DoCoMoHandler) FixedIds() []string{
+	return dh.ConstantIds
 }
 
-func (h *DoCoMoHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
+func (dh *DoCoMoHandler) CanHandle(ua string) bool {
+	if dh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return h.OrderedUAS
+	return dh.util.CheckIfStartsWith(ua,"DoCoMo")
 }
 
-func(h *DoCoMoHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+func (dh *DoCoMoHandler) Tolerance(ua string) int{
+	tolerance := dh.util.OrdinalIndexOf(ua,"/",2)
+	if tolerance == -1 {
+		tolerance = dh.util.IndexOfOrLength(ua,"(",0)
+
 	}
-	return NO_MATCH
+	return tolerance
 }
-func(h *DoCoMoHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+
+func (dh *DoCoMoHandler) ApplyRecoveryMatch(ua string) string {
+	// The version follows "DoCoMo/".
+	verIdx := 7
+	if len(ua) > verIdx && ua[verIdx] == '2'{
+		return "docomo_generic_jap_ver2"
 	}
-	return NO_MATCH
+	return "docomo_generic_jap_ver1"
 }
 
-
-func (h *DoCoMoHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
+type FirefoxHandler struct{
+	BaseHandler
+	ConstantIds []string
 }
 
-func (h *DoCoMoHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
+func NewFirefoxHandler(norm Normalizer) *FirefoxHandler{
+	fh := new(FirefoxHandler)
+	fh.Init(fh, norm)
+	fh.ConstantIds = []string{
+		"firefox",
+		"firefox_1",
+        "firefox_2",
+        "firefox_3",
+        "firefox_4_0",
+        "firefox_5_0",
+        "firefox_6_0",
+        "firefox_7_0",
+        "firefox_8_0",
+        "firefox_9_0",
+        "firefox_10_0",
+        "firefox_11_0",
+        "firefox_12_0",
+	}
+	return fh
 }
 
-func (dh *DoCoMoHandler) SetNextHandler(hlr Handlers){
-	dh.nextHandler = hlr
+func (fh *FirefoxHandler) FixedIds() []string{
+	return fh.ConstantIds
 }
 
-func (h *DoCoMoHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
+func (fh *FirefoxHandler) CanHandle(ua string) bool{
+	if fh.util.IsMobileBrowser(ua){
+		return false
 	}
-	return NO_MATCH
-}
-
-func (h *DoCoMoHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *DoCoMoHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *DoCoMoHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (dh *DoCoMoHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfStartsWith(ua,"DoCoMo")
-}
-
-func (dh *DoCoMoHandler) ApplyConclusiveMatch(ua string) string {
-	tolerance := util.OrdinalIndexOf(ua,"/",2)
-	if tolerance == -1 {
-		tolerance = util.IndexOfOrLength(ua,"(",0)
-
-	}
-	return dh.GetDeviceIdFromRIS(ua,tolerance)
-}
-
-func (dh *DoCoMoHandler) ApplyRecoveryMatch(ua string) string {
-	verIdx := 7
-	version := ua[verIdx]
-	if version == '2'{
-		return "docomo_generic_jap_ver2"
-	}
-	return "docomo_generic_jap_ver1"
-}
-
-type FirefoxHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	ConstantIds []string
-}
-
-func NewFirefoxHandler(norm Normalizer) *FirefoxHandler{
-	fh := new(FirefoxHandler)
-	fh.ConstantIds = []string{
-		"firefox",
-		"firefox_1",
-        "firefox_2",
-        "firefox_3",
-        "firefox_4_0",
-        "firefox_5_0",
-        "firefox_6_0",
-        "firefox_7_0",
-        "firefox_8_0",
-        "firefox_9_0",
-        "firefox_10_0",
-        "firefox_11_0",
-        "firefox_12_0",
-	}
-	fh.Normalizer = norm
-	fh.OrderedUAS = []string{}
-	fh.UASWithDeviceId = make(map[string]string)
-	return fh
-}
-
-
-func (h *FirefoxHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *FirefoxHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *FirefoxHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *FirefoxHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-func (h *FirefoxHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (fh *FirefoxHandler) CanHandle(ua string) bool{
-	if util.IsMobileBrowser(ua){
-		return false
-	}
-	if util.CheckIfContainsAnyOf(ua,[]string{"Tablet", "Sony", "Novarra", "Opera"}){
+	if fh.util.CheckIfContainsAnyOf(ua,[]string{"Tablet", "Sony", "Novarra", "Opera"}){
 		return false
 	}
-	return util.CheckIfContains(ua, "Firefox")
-}
-
-func (h *FirefoxHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *FirefoxHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (fh *FirefoxHandler) SetNextHandler(hlr Handlers){
-	fh.nextHandler = hlr
-}
-
-func (h *FirefoxHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *FirefoxHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
+	return fh.util.CheckIfContains(ua, "Firefox")
 }
 
-func (h *FirefoxHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
+func (fh *FirefoxHandler) Tolerance(ua string) int{
+	return fh.util.IndexOfOrLength(ua,".",0)
 }
 
-func (fh *FirefoxHandler) ApplyConclusiveMatch(ua string) string {
-	return fh.GetDeviceIdFromRIS(ua,util.IndexOfOrLength(ua,".",0))
-}
+var firefoxVersionRx = regexp.MustCompile(`Firefox\/(\d+)\.\d`)
 
 func (fh *FirefoxHandler) ApplyRecoveryMatch(ua string)string {
-	wordRx := regexp.MustCompile(`Firefox\/(\d+)\.\d`)
-	matches := wordRx.FindStringSubmatch(ua)
+	matches := firefoxVersionRx.FindStringSubmatch(ua)
 	if len(matches) > 0 {
 		var id string
 		firefoxVersion := matches[1]
@@ -2013,5526 +1070,1126 @@ func (fh *FirefoxHandler) ApplyRecoveryMatch(ua string)string {
 }
 
 type GrundigHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+	BaseHandler
 }
 
 func NewGrundigHandler(norm Normalizer) *GrundigHandler{
 	gh := new(GrundigHandler)
-	gh.Normalizer = norm
-	gh.OrderedUAS = []string{}
-	gh.UASWithDeviceId = make(map[string]string)
+	gh.Init(gh, norm)
 	return gh
 }
 
-func (h *GrundigHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *GrundigHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *GrundigHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
+func (gh *GrundigHandler) CanHandle(ua string) bool {
+	if gh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return h.OrderedUAS
+	return gh.util.CheckIfStartsWithAnyOf(ua,[]string{"Grundig","GRUNDIG"})
 }
 
-func(h *GrundigHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
+type HTCHandler struct{
+	BaseHandler
 }
-func(h *GrundigHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
+func NewHTCHandler(norm Normalizer) *HTCHandler{
+	hh := new(HTCHandler)
+	hh.Init(hh, norm)
+	return hh
 }
 
-func (h *GrundigHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
+func (hh *HTCHandler) CanHandle(ua string) bool{
+	if hh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return NO_MATCH
+	return hh.util.CheckIfContainsAnyOf(ua,[]string{"HTC","XV6875"})
 }
 
-func (h *GrundigHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
+type HTCMacHandler struct{
+	BaseHandler
+	ConstantIds []string
 }
 
-func (h *GrundigHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
+func NewHTCMacHandler(norm Normalizer) *HTCMacHandler{
+	htcMacHandler := new(HTCMacHandler)
+	htcMacHandler.Init(htcMacHandler, norm)
+	htcMacHandler.ConstantIds = []string{
+		"generic_android_htc_disguised_as_mac",
+	}
+	return htcMacHandler
 }
 
-func (gh *GrundigHandler) SetNextHandler(hlr Handlers){
-	gh.nextHandler = hlr
+func (htcm *HTCMacHandler) FixedIds() []string{
+	return htcm.ConstantIds
 }
 
-func (h *GrundigHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
+func (htcm *HTCMacHandler) CanHandle(ua string) bool {
+	return htcm.util.CheckIfStartsWith(ua,"Mozilla/5.0 (Macintosh") || htcm.util.CheckIfContains(ua,"HTC")
 }
 
-func (h *GrundigHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
+func (htcm *HTCMacHandler) ApplyConclusiveMatch(ua string)string {
+	delimiterIdx := strings.Index(ua,RIS_DELIMITER)
+	if delimiterIdx != -1{
+		tolerance := delimiterIdx + len(RIS_DELIMITER)
+		return htcm.GetDeviceIdFromRIS(ua,tolerance)
 	}
 	return NO_MATCH
 }
 
-func (h *GrundigHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
+func (htcm *HTCMacHandler) ApplyRecoveryMatch(ua string) string {
+	return "generic_android_htc_disguised_as_mac"
 }
 
-func (h *GrundigHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
+var (
+	htcMacModelRx = regexp.MustCompile(`(HTC[^;\)]+)`)
+	htcMacSeparatorRx = regexp.MustCompile(`[ _\-/]`)
+)
 
-func (gh *GrundigHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
+func (htcm *HTCMacHandler) GetHTCMacModel(ua string) string{
+	matches := htcMacModelRx.FindStringSubmatch(ua)
+	if len(matches) > 0{
+		model := htcMacSeparatorRx.ReplaceAllString(matches[1],"~")
+		return model
 	}
-	return util.CheckIfStartsWithAnyOf(ua,[]string{"Grundig","GRUNDIG"})
-}
-
-type HTCHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-func NewHTCHandler(norm Normalizer) *HTCHandler{
-	hh := new(HTCHandler)
-	hh.Normalizer = norm
-	hh.OrderedUAS = []string{}
-	hh.UASWithDeviceId = make(map[string]string)
-	return hh
-}
-
-func (h *HTCHandler) ApplyRecoveryMatch(ua string) string{
 	return NO_MATCH
 }
-func (h *HTCHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
 
-func (h *HTCHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
+type JavaMidletHandler struct{
+	BaseHandler
+	ConstantIds []string
 }
 
-func(h *HTCHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *HTCHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+func NewJavaMidletHandler(norm Normalizer) *JavaMidletHandler{
+	jmh := new(JavaMidletHandler)
+	jmh.Init(jmh, norm)
+	jmh.ConstantIds = []string{
+		"generic_midp_midlet",
 	}
-	return NO_MATCH
+	return jmh
 }
 
-func (h *HTCHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
+func (jmh *JavaMidletHandler) FixedIds() []string{
+	return jmh.ConstantIds
 }
 
-func (h *HTCHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
+func (jmh *JavaMidletHandler) CanHandle(ua string) bool {
+	return jmh.util.CheckIfContains(ua,"UNTRUSTED/1.0")
 }
 
-func (h *HTCHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
+func (jmh *JavaMidletHandler) ApplyConclusiveMatch(ua string) string {
+	return "generic_midp_midlet"
 }
 
-func (h *HTCHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
+type KDDIHandler struct{
+	BaseHandler
+	ConstantIds []string
 }
 
-func (h *HTCHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
+func NewKDDIHandler(norm Normalizer) *KDDIHandler{
+	kdh := new(KDDIHandler)
+	kdh.Init(kdh, norm)
+	kdh.ConstantIds = []string{
+		"opwv_v62_generic",
 	}
-	return deviceId
+	return kdh
 }
 
-func (hh *HTCHandler) SetNextHandler(hlr Handlers){
-	hh.nextHandler = hlr
+func (kdh *KDDIHandler) FixedIds() []string{
+	return kdh.ConstantIds
 }
-func (h *HTCHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
+
+func (kdh *KDDIHandler) CanHandle(ua string) bool {
+	if kdh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return
+	return kdh.util.CheckIfContains(ua,"KDDI-")
 }
 
-func (h *HTCHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
+func (kdh *KDDIHandler) Tolerance(ua string) int{
+	var tolerance int
+	if kdh.util.CheckIfStartsWith(ua,"KDDI/"){
+		tolerance = kdh.util.SecondSlash(ua)
+	} else {
+		tolerance = kdh.util.FirstSlash(ua)
 	}
-	return GENERIC
+	return tolerance
 }
 
-func (hh *HTCHandler) CanHandle(ua string) bool{
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfContainsAnyOf(ua,[]string{"HTC","XV6875"})
+func (kdh *KDDIHandler) ApplyRecoveryMatch(ua string) string {
+	return "opwv_v62_generic"
 }
 
-type HTCMacHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+type KindleHandler struct{
+	BaseHandler
 	ConstantIds []string
 }
 
-func NewHTCMacHandler(norm Normalizer) *HTCMacHandler{
-	htcMacHandler := new(HTCMacHandler)
-	htcMacHandler.ConstantIds = []string{
-		"generic_android_htc_disguised_as_mac",
+func NewKindleHandler(norm Normalizer) *KindleHandler{
+	kh := new(KindleHandler)
+	kh.Init(kh, norm)
+	kh.ConstantIds = []string{
+		"amazon_kindle_ver1",
+        "amazon_kindle2_ver1",
+        "amazon_kindle3_ver1",
+        "amazon_kindle_fire_ver1",
+        "generic_amazon_android_kindle",
+        "generic_amazon_kindle",
 	}
-	htcMacHandler.Normalizer = norm
-	htcMacHandler.OrderedUAS = []string{}
-	htcMacHandler.UASWithDeviceId = make(map[string]string)
-	return htcMacHandler
+	return kh
 }
 
+func (kh *KindleHandler) FixedIds() []string{
+	return kh.ConstantIds
+}
 
-func (h *HTCMacHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *HTCMacHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *HTCMacHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *HTCMacHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-
-func (h *HTCMacHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *HTCMacHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *HTCMacHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (htcm *HTCMacHandler) SetNextHandler(hlr Handlers){
-	htcm.nextHandler = hlr
-}
-func (h *HTCMacHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *HTCMacHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *HTCMacHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (htcm *HTCMacHandler) CanHandle(ua string) bool {
-	return util.CheckIfStartsWith(ua,"Mozilla/5.0 (Macintosh") || util.CheckIfContains(ua,"HTC")
-}
-
-func (htcm *HTCMacHandler) ApplyConclusiveMatch(ua string)string {
-	delimiterIdx := strings.Index(ua,RIS_DELIMITER)
-	if delimiterIdx != -1{
-		tolerance := delimiterIdx + len(RIS_DELIMITER)
-		return htcm.GetDeviceIdFromRIS(ua,tolerance)
-	}
-	return NO_MATCH
-}
-
-func (htcm *HTCMacHandler) ApplyRecoveryMatch(ua string) string {
-	return "generic_android_htc_disguised_as_mac"
-}
-
-func (htcm *HTCMacHandler) GetHTCMacModel(ua string) string{
-	wordRx := regexp.MustCompile(`(HTC[^;\)]+)`)
-	matches := wordRx.FindStringSubmatch(ua)
-	if len(matches) > 0{
-		modelRx := regexp.MustCompile(`[ _\-/]`)
-		model := modelRx.ReplaceAllString(matches[1],"~")
-		return model
-	}
-	return NO_MATCH
-}
-
-type JavaMidletHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	ConstantIds []string
-}
-
-
-func NewJavaMidletHandler(norm Normalizer) *JavaMidletHandler{
-	jmh := new(JavaMidletHandler)
-	jmh.Normalizer = norm
-	jmh.ConstantIds = []string{
-		"generic_midp_midlet",
-	}
-	jmh.OrderedUAS = []string{}
-	jmh.UASWithDeviceId = make(map[string]string)
-	return jmh
-}
-
-func (h *JavaMidletHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *JavaMidletHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *JavaMidletHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *JavaMidletHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *JavaMidletHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-func (h *JavaMidletHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *JavaMidletHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *JavaMidletHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *JavaMidletHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *JavaMidletHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (jmh *JavaMidletHandler) SetNextHandler(hlr Handlers){
-	jmh.nextHandler = hlr
-}
-
-func (h *JavaMidletHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (jmh *JavaMidletHandler) CanHandle(ua string) bool {
-	return util.CheckIfContains(ua,"UNTRUSTED/1.0")
-}
-
-func (jmh *JavaMidletHandler) ApplyConclusiveMatch(ua string) string {
-	return "generic_midp_midlet"
-}
-
-type KDDIHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	ConstantIds []string
-}
-
-func NewKDDIHandler(norm Normalizer) *KDDIHandler{
-	kdh := new(KDDIHandler)
-	kdh.ConstantIds = []string{
-		"opwv_v62_generic",
-	}
-	kdh.Normalizer = norm
-	kdh.OrderedUAS = []string{}
-	kdh.UASWithDeviceId = make(map[string]string)
-	return kdh
-}
-
-
-func (h *KDDIHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *KDDIHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *KDDIHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *KDDIHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *KDDIHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-
-
-func (h *KDDIHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *KDDIHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (kdh *KDDIHandler) SetNextHandler(hlr Handlers){
-	kdh.nextHandler = hlr
-}
-
-func (h *KDDIHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *KDDIHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *KDDIHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (kdh *KDDIHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfContains(ua,"KDDI-")
-}
-
-func (kdh *KDDIHandler) ApplyConclusiveMatch(ua string) string {
-	var tolerance int
-	if util.CheckIfStartsWith(ua,"KDDI/"){
-		tolerance = util.SecondSlash(ua)
-	} else {
-		tolerance = util.FirstSlash(ua)
-	}
-	return kdh.GetDeviceIdFromRIS(ua,tolerance)
-}
-
-func (kdh *KDDIHandler) ApplyRecoveryMatch(ua string) string {
-	return "opwv_v62_generic"
-}
-
-type KindleHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	ConstantIds []string
-}
-
-func NewKindleHandler(norm Normalizer) *KindleHandler{
-	kh := new(KindleHandler)
-	kh.ConstantIds = []string{
-		"amazon_kindle_ver1",
-        "amazon_kindle2_ver1",
-        "amazon_kindle3_ver1",
-        "amazon_kindle_fire_ver1",
-        "generic_amazon_android_kindle",
-        "generic_amazon_kindle",
-	}
-	kh.Normalizer = norm
-	kh.OrderedUAS = []string{}
-	kh.UASWithDeviceId = make(map[string]string)
-	return kh
-}
-
-func (h *KindleHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *KindleHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *KindleHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *KindleHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-
-func (h *KindleHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *KindleHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *KindleHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *KindleHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *KindleHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *KindleHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (kh *KindleHandler) SetNextHandler(hlr Handlers){
-	kh.nextHandler = hlr
-}
-
-func (kh *KindleHandler) CanHandle(ua string) bool {
-	return util.CheckIfContainsAnyOf(ua,[]string{"Kindle","Silk"})
-}
-
-func (kh *KindleHandler) ApplyConclusiveMatch(ua string) string {
-	search := "Kindle/"
-	idx := strings.Index(ua,search)
-	var tolerance int
-	if idx != -1 {
-	
-        tolerance = idx + len(search) + 1
-        kindleVersion := ua[tolerance]
-        // RIS match only Kindle/1-3
-        if kindleVersion >= 1 && kindleVersion <= 3{
-            return kh.GetDeviceIdFromRIS(ua, tolerance)
-        }
-	}
-	delimiterIdx := strings.Index(ua,RIS_DELIMITER)
-	if delimiterIdx != -1 {
-		tolerance = delimiterIdx + len(RIS_DELIMITER)
-		return kh.GetDeviceIdFromRIS(ua,tolerance)
-	}
-	return NO_MATCH
-
-}
-
-func (kh *KindleHandler) ApplyRecoveryMatch(ua string)string {
-	if util.CheckIfContains(ua,"Kindle/1"){
-		return "amazon_kindle_ver1"
-	}
-	if util.CheckIfContains(ua,"Kindle/2"){
-		return "amazon_kindle2_ver1"
-	}
-	if util.CheckIfContains(ua, "Kindle/3"){
-		return "amazon_kindle3_ver1"
-	}
-	if util.CheckIfContainsAnyOf(ua,[]string{"Kindle Fire","Silk"}){
-		return "amazon_kindle_fire_ver1"
-	}
-	return "generic_amazon_kindle"
-}
-
-
-type KonquerorHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-
-func NewKonquerorHandler(norm Normalizer) *KonquerorHandler{
-	kqh := new(KonquerorHandler)
-	kqh.Normalizer = norm
-	kqh.OrderedUAS = []string{}
-	kqh.UASWithDeviceId = make(map[string]string)
-	return kqh
-}
-
-func (h *KonquerorHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *KonquerorHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *KonquerorHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *KonquerorHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *KonquerorHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *KonquerorHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *KonquerorHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *KonquerorHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *KonquerorHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *KonquerorHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *KonquerorHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *KonquerorHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (kqh *KonquerorHandler) SetNextHandler(hlr Handlers){
-	kqh.nextHandler = hlr
-}
-
-func (kqh *KonquerorHandler) CanHandle(ua string) bool {
-	if util.IsMobileBrowser(ua){
-		return false
-	}
-	return util.CheckIfContains(ua,"Konqueror")
-}
-
-type KyoceraHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-
-func NewKyoceraHandler(norm Normalizer) *KyoceraHandler{
-	kyh := new(KyoceraHandler)
-	kyh.Normalizer = norm
-	kyh.OrderedUAS = []string{}
-	kyh.UASWithDeviceId = make(map[string]string)
-	return kyh
-}
-
-func (h *KyoceraHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *KyoceraHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *KyoceraHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *KyoceraHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *KyoceraHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *KyoceraHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *KyoceraHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *KyoceraHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *KyoceraHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *KyoceraHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *KyoceraHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *KyoceraHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *KyoceraHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-func (kyh *KyoceraHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfStartsWithAnyOf(ua,[]string{"kyocera", "QC-", "KWC-"})
-}
-
-type LGHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-
-func NewLGHandler(norm Normalizer) *LGHandler{
-	lgh := new(LGHandler)
-	lgh.Normalizer = norm
-	lgh.OrderedUAS = []string{}
-	lgh.UASWithDeviceId = make(map[string]string)
-	return lgh
-}
-
-
-func (h *LGHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *LGHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *LGHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *LGHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-
-func (h *LGHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *LGHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *LGHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *LGHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *LGHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *LGHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *LGHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-func (lgh *LGHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfContainsAnyOf(ua,[]string{"lg","LG"})
-}
-
-func (lgh *LGHandler) ApplyConclusiveMatch(ua string) string {
-	tolerance := util.IndexOfOrLength(ua,"/",strings.Index(strings.ToUpper(ua),"LG"))
-	return lgh.GetDeviceIdFromRIS(ua,tolerance)
-}
-
-func (lgh *LGHandler) ApplyRecoveryMatch(ua string) string {
-	return lgh.GetDeviceIdFromRIS(ua,7)
-}
-
-
-type LGPLUSHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	ConstantIds []string
-	lgPluses map[string][]string
-}
-
-func NewLGPLUSHandler(norm Normalizer) *LGPLUSHandler{
-	lgph := new(LGPLUSHandler)
-	lgph.ConstantIds = []string{
-		"generic_lguplus_rexos_facebook_browser",
-        "generic_lguplus_rexos_webviewer_browser",
-        "generic_lguplus_winmo_facebook_browser",
-        "generic_lguplus_android_webkit_browser",
-	}
-	lgph.lgPluses = map[string][]string{
-		"generic_lguplus_rexos_facebook_browser": []string{
-            "Windows NT 5",
-            "POLARIS",
-        },
-        "generic_lguplus_rexos_webviewer_browser": []string{
-            "Windows NT 5",
-        },
-        "generic_lguplus_winmo_facebook_browser": []string{
-            "Windows CE",
-            "POLARIS",
-        },
-        "generic_lguplus_android_webkit_browser": []string{
-            "Android",
-            "AppleWebKit",
-        },
-	}
-	lgph.Normalizer = norm
-	lgph.OrderedUAS = []string{}
-	lgph.UASWithDeviceId = make(map[string]string)
-	return lgph
-}
-
-
-func (h *LGPLUSHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *LGPLUSHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *LGPLUSHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *LGPLUSHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-
-func (h *LGPLUSHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *LGPLUSHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *LGPLUSHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-func (h *LGPLUSHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *LGPLUSHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *LGPLUSHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *LGPLUSHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (lgph *LGPLUSHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfContainsAnyOf(ua,[]string{"LGUPLUS","lgtelecom"})
-}
-
-func (lgph *LGPLUSHandler) ApplyConclusiveMatch(ua string) string {
-	return NO_MATCH
-}
-
-func (lgph *LGPLUSHandler) ApplyRecoveryMatch(ua string) string {
-	for deviceId, values := range lgph.lgPluses{
-		if util.CheckIfContainsAll(ua,values){
-			return deviceId
-		}
-	}
-	return NO_MATCH
-}
-
-type MSIEHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	ConstantIds []string
-}
-
-func NewMSIEHandler(norm Normalizer) *MSIEHandler{
-	msieh := new(MSIEHandler)
-	msieh.ConstantIds = []string{
-		"msie",
-        "msie_4",
-        "msie_5",
-        "msie_5_5",
-        "msie_6",
-        "msie_7",
-        "msie_8",
-        "msie_9",
-	}
-	msieh.Normalizer = norm
-	msieh.OrderedUAS = []string{}
-	msieh.UASWithDeviceId = make(map[string]string)
-	return msieh
-}
-
-func (h *MSIEHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-
-func (h *MSIEHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *MSIEHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *MSIEHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *MSIEHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-
-func (h *MSIEHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *MSIEHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *MSIEHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *MSIEHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *MSIEHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *MSIEHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (msieh *MSIEHandler) CanHandle(ua string) bool {
-	if util.IsMobileBrowser(ua){
-		return false
-	}
-	if util.CheckIfContainsAnyOf(ua,[]string{"Opera", "armv", "MOTO", "BREW"}){
-		return false
-	}
-	return util.CheckIfStartsWith(ua,"Mozilla") && util.CheckIfContains(ua,"MSIE")
-}
-
-func (h *MSIEHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (msieh *MSIEHandler) ApplyConclusiveMatch(ua string) string {
-	wordRx := regexp.MustCompile(`^Mozilla\/4\.0 \(compatible; MSIE (\d)\.(\d);`)
-	matches := wordRx.FindStringSubmatch(ua)
-	if len(matches) > 0{
-		value,_ := strconv.Atoi(matches[1])
-		if value == 7{
-			return "msie_7"
-		} else if value == 8 {
-			return "msie_8"
-		} else if value == 9 {
-			return "msie_9"
-		} else if value == 6 {
-			return "msie_6"
-		} else if value == 5 {
-			if matches[2] == "5"{
-				return "msie_5_5"
-			}
-			return "msie_5"
-		} else {
-			return "msie"
-		}
-	}
-	tolerance := util.FirstSlash(ua)
-	return msieh.GetDeviceIdFromRIS(ua,tolerance)
-}
-
-type MitsubishiHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-
-func NewMitsubishiHandler(norm Normalizer) *MitsubishiHandler{
-	mh := new(MitsubishiHandler)
-	mh.Normalizer = norm
-	mh.OrderedUAS = []string{}
-	mh.UASWithDeviceId = make(map[string]string)
-	return mh
-}
-
-func (h *MitsubishiHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *MitsubishiHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *MitsubishiHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *MitsubishiHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *MitsubishiHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-func (h *MitsubishiHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *MitsubishiHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *MitsubishiHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *MitsubishiHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *MitsubishiHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *MitsubishiHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *MitsubishiHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (mh *MitsubishiHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfStartsWith(ua,"Mitsu")
-}
-
-func (mh *MitsubishiHandler) ApplyConclusiveMatch(ua string) string {
-	tolerance := util.FirstSpace(ua)
-	return mh.GetDeviceIdFromRIS(ua,tolerance) 
-}
-
-type MotorolaHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	ConstantIds []string
-}
-
-func NewMotorolaHandler(norm Normalizer) *MotorolaHandler{
-	mh := new(MotorolaHandler)
-	mh.Normalizer = norm
-	mh.ConstantIds = []string{
-		"mot_mib22_generic",
-	}
-	mh.OrderedUAS = []string{}
-	mh.UASWithDeviceId = make(map[string]string)
-	return mh
-}
-
-
-func (h *MotorolaHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *MotorolaHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *MotorolaHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *MotorolaHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-
-func (h *MotorolaHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *MotorolaHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *MotorolaHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *MotorolaHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *MotorolaHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *MotorolaHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *MotorolaHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (mh *MotorolaHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfStartsWithAnyOf(ua,[]string{"Mot-","MOT-", "MOTO", "moto"}) || util.CheckIfContains(ua,"Motorola")
-}
-
-func (mh *MotorolaHandler) ApplyConclusiveMatch(ua string) string {
-	if util.CheckIfStartsWithAnyOf(ua, []string{"Mot-","MOT-", "Motorola"}){
-		return mh.GetDeviceIdFromRIS(ua,util.FirstSlash(ua))
-	}
-	return mh.GetDeviceIdFromRIS(ua,5)
-}
-
-func (mh *MotorolaHandler) ApplyRecoveryMatch(ua string)string {
-	if util.CheckIfContainsAnyOf(ua,[]string{"MIB/2.2","MIB/BER2.2"}){
-		return "mot_mib22_generic"
-	}
-	return NO_MATCH
-}
-
-type NecHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	NecKgtTolerance int
-}
-
-func NewNecHandler(norm Normalizer) *NecHandler{
-	nh := new(NecHandler)
-	nh.NecKgtTolerance = 2
-	nh.Normalizer = norm
-	nh.OrderedUAS = []string{}
-	nh.UASWithDeviceId = make(map[string]string)
-	return nh
-}
-
-func (h *NecHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-
-func (h *NecHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *NecHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *NecHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *NecHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-func (h *NecHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *NecHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *NecHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *NecHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *NecHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *NecHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *NecHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (nh *NecHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfStartsWithAnyOf(ua,[]string{"NEC-","KGT"})
-}
-
-func (nh *NecHandler) ApplyConclusiveMatch(ua string) string {
-	if util.CheckIfStartsWith(ua,"NEC-1"){
-		tolerance := util.FirstSlash(ua)
-		return nh.GetDeviceIdFromRIS(ua,tolerance)
-	}
-	return nh.GetDeviceIdFromRIS(ua,nh.NecKgtTolerance)
-}
-
-
-type NintendoHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	ConstantIds []string
-}
-
-func NewNintendoHandler(norm Normalizer) *NintendoHandler{
-	nh := new(NintendoHandler)
-	nh.ConstantIds = []string{
-		"nintendo_wii_ver1",
-        "nintendo_dsi_ver1",
-		"nintendo_ds_ver1",
-	}
-	nh.Normalizer = norm
-	nh.OrderedUAS = []string{}
-	nh.UASWithDeviceId = make(map[string]string)
-	return nh
-}
-
-func (h *NintendoHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *NintendoHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *NintendoHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *NintendoHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-func (h *NintendoHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *NintendoHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *NintendoHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *NintendoHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *NintendoHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *NintendoHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *NintendoHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (nh *NintendoHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	if util.CheckIfContains(ua,"Nintendo"){
-		return true
-	}
-	return util.CheckIfStartsWith(ua,"Mozilla") && util.CheckIfContainsAll(ua,[]string{"Nitro", "Opera"})
-}
-
-func (nh *NintendoHandler) ApplyConclusiveMatch(ua string) string {
-	return nh.GetDeviceIdFromLD(ua,0)
-}
-
-func (nh *NintendoHandler) ApplyRecoveryMatch(ua string) string {
-	if util.CheckIfContains(ua,"Nintendo Wii"){
-		return "nintendo_wii_ver1"
-	}
-	if  util.CheckIfContains(ua,"Nintendo DSi"){
-		return "nintendo_dsi_ver1"
-	}
-	if util.CheckIfStartsWith(ua,"Mozilla") && util.CheckIfContainsAll(ua,[]string{"Nitro", "Opera"}){
-		return "nintendo_ds_ver1"
-	}
-	return "nintendo_wii_ver1"
-}
-
-
-type NokiaHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	ConstantIds []string
-}
-
-func NewNokiaHandler(norm Normalizer) *NokiaHandler{
-	nh := new(NokiaHandler)
-	nh.ConstantIds = []string{
-		"nokia_generic_series60",
-		"nokia_generic_series80",
-		"nokia_generic_meego",
-	}
-	nh.Normalizer = norm
-	nh.OrderedUAS = []string{}
-	nh.UASWithDeviceId = make(map[string]string)
-	return nh
-}
-
-func (h *NokiaHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *NokiaHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *NokiaHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *NokiaHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *NokiaHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *NokiaHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *NokiaHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *NokiaHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *NokiaHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *NokiaHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *NokiaHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *NokiaHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (nh *NokiaHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfContains(ua,"Nokia")
-}
-
-
-func (nh *NokiaHandler) ApplyRecoveryMatch(ua string) string {
-	if util.CheckIfContains(ua,"Series60"){
-		return "nokia_generic_series60"
-	}
-	if util.CheckIfContains(ua,"Series80"){
-		return "nokia_generic_series80"
-	}
-	if util.CheckIfContains(ua,"MeeGo"){
-		return "nokia_generic_meego"
-	}
-	return NO_MATCH
-}
-
-type NokiaOviBrowserHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	ConstantIds []string
-}
-
-func NewNokiaOviBrowserHandler(norm Normalizer) *NokiaOviBrowserHandler{
-	novih := new(NokiaOviBrowserHandler)
-	novih.ConstantIds = []string{
-		"nokia_generic_series40_ovibrosr",
-	}
-	novih.Normalizer = norm
-	novih.OrderedUAS = []string{}
-	novih.UASWithDeviceId = make(map[string]string)
-	return novih
-}
-
-func (h *NokiaOviBrowserHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *NokiaOviBrowserHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *NokiaOviBrowserHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *NokiaOviBrowserHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *NokiaOviBrowserHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-func (h *NokiaOviBrowserHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *NokiaOviBrowserHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *NokiaOviBrowserHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *NokiaOviBrowserHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *NokiaOviBrowserHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *NokiaOviBrowserHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *NokiaOviBrowserHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (novih *NokiaOviBrowserHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfContains(ua,"S40OviBrowser")
-}
-
-func (novih *NokiaOviBrowserHandler) ApplyConclusiveMatch(ua string) string {
-	idx := strings.Index(ua,"Nokia")
-	if idx == -1 {
-		return NO_MATCH
-	}
-	tolerance := util.IndexOfAnyOrLength(ua,[]string{"/"," "},idx)
-	return novih.GetDeviceIdFromRIS(ua,tolerance)
-}
-
-type OperaHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	ConstantIds []string
-}
-
-func NewOperaHandler(norm Normalizer) *OperaHandler{
-	oh := new(OperaHandler)
-	oh.ConstantIds = []string{
-		"opera",
-        "opera_7",
-        "opera_8",
-        "opera_9",
-        "opera_10",
-        "opera_11",
-		"opera_12",
-	}
-	oh.Normalizer = norm
-	oh.OrderedUAS = []string{}
-	oh.UASWithDeviceId = make(map[string]string)
-	return oh
-}
-
-func (h *OperaHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *OperaHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *OperaHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *OperaHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-func (h *OperaHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *OperaHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *OperaHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *OperaHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *OperaHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *OperaHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *OperaHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (oh *OperaHandler) CanHandle(ua string) bool {
-	if util.IsMobileBrowser(ua){
-		return false
-	}
-	return util.CheckIfContains(ua,"Opera")
-}
-
-func (oh *OperaHandler) ApplyConclusiveMatch(ua string) string {
-	opIdx := strings.Index(ua,"Opera")
-	tolerance := util.IndexOfOrLength(ua,".",opIdx)
-	return oh.GetDeviceIdFromRIS(ua,tolerance)
-}
-
-func (oh *OperaHandler) ApplyRecoveryMatch(ua string) string {
-	operaVersion := oh.GetOperaVersion(ua)
-	if operaVersion == 0.0{
-		return "opera"
-	}
-	MajorVersion := math.Floor(operaVersion)
-	
-	strVer:= strconv.FormatFloat(MajorVersion,[]byte("f")[0],1,64)
-	id := "opera_" + strVer
-	for k := range oh.ConstantIds{
-		if oh.ConstantIds[k] == id{
-			return id
-		}
-	}
-	return "opera"
-}
-
-func (oh *OperaHandler) GetOperaVersion(ua string) float64 {
-	wordRx := regexp.MustCompile(`Opera[ /]?(\d+\.\d+)`)
-	matches := wordRx.FindStringSubmatch(ua)
-	var ver float64
-	if len(matches) > 0{
-		ver,_=strconv.ParseFloat(matches[1],64)
-		return ver
-	}
-	return float64(0.0)
-}
-
-type OperaMiniHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	operaMinis map[string]string
-}
-
-func NewOperaMiniHandler(norm Normalizer) *OperaMiniHandler{
-	omh := new(OperaMiniHandler)
-	omh.operaMinis = map[string]string{
-		"Opera Mini/1": "generic_opera_mini_version1",
-        "Opera Mini/2": "generic_opera_mini_version2",
-        "Opera Mini/3": "generic_opera_mini_version3",
-        "Opera Mini/4": "generic_opera_mini_version4",
-        "Opera Mini/5": "generic_opera_mini_version5",
-	}
-	omh.Normalizer = norm
-	omh.OrderedUAS = []string{}
-	omh.UASWithDeviceId = make(map[string]string)
-	return omh
-}
-
-func (h *OperaMiniHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *OperaMiniHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *OperaMiniHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *OperaMiniHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *OperaMiniHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *OperaMiniHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *OperaMiniHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *OperaMiniHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *OperaMiniHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *OperaMiniHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *OperaMiniHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *OperaMiniHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (omh *OperaMiniHandler) CanHandle(ua string) bool {
-	return util.CheckIfContains(ua,"Opera Mini")
-}
-
-func (omh *OperaMiniHandler) ApplyRecoveryMatch(ua string) string {
-	for key,deviceId := range omh.operaMinis{
-		if util.CheckIfContains(ua,key){
-			return deviceId
-		}
-	}
-	if util.CheckIfContains(ua,"Opera Mobi"){
-		return "generic_opera_mini_version4"
-	}
-	return "generic_opera_mini_version1"
-}
-
-type PanasonicHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-
-func NewPanasonicHandler(norm Normalizer) *PanasonicHandler {
-	ph := new(PanasonicHandler)
-	ph.Normalizer = norm
-	ph.OrderedUAS = []string{}
-	ph.UASWithDeviceId = make(map[string]string)
-	return ph
-}
-
-func (h *PanasonicHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *PanasonicHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *PanasonicHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *PanasonicHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *PanasonicHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *PanasonicHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *PanasonicHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *PanasonicHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *PanasonicHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *PanasonicHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *PanasonicHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *PanasonicHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *PanasonicHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (ph *PanasonicHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfStartsWith(ua,"Panasonic")
-}
-
-type PantechHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	PantechTolerance int
-}
-
-func NewPantechHandler(norm Normalizer) *PantechHandler{
-	ph := new(PantechHandler)
-	ph.PantechTolerance = 5
-	ph.Normalizer = norm
-	ph.OrderedUAS = []string{}
-	ph.UASWithDeviceId = make(map[string]string)
-	return ph
-}
-
-func (h *PantechHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *PantechHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *PantechHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *PantechHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *PantechHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-func (h *PantechHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *PantechHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *PantechHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-
-func (ph *PantechHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfStartsWithAnyOf(ua,[]string{"Pantech","PT-","PANTECH","PG-"})
-}
-
-func (h *PantechHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *PantechHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *PantechHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *PantechHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (ph *PantechHandler) ApplyConclusiveMatch(ua string) string {
-	var tolerance int
-	if util.CheckIfStartsWith(ua,"Pantech"){
-		tolerance = ph.PantechTolerance
-	} else {
-		tolerance = util.FirstSlash(ua)
-	}
-	return ph.GetDeviceIdFromRIS(ua,tolerance)
-}
-
-
-type PhilipsHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-
-func NewPhilipsHandler(norm Normalizer) *PhilipsHandler{
-	ph := new(PhilipsHandler)
-	ph.Normalizer = norm
-	ph.OrderedUAS = []string{}
-	ph.UASWithDeviceId = make(map[string]string)
-	return ph
-}
-
-func (h *PhilipsHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *PhilipsHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *PhilipsHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *PhilipsHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *PhilipsHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *PhilipsHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *PhilipsHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *PhilipsHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *PhilipsHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *PhilipsHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *PhilipsHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *PhilipsHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *PhilipsHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (ph *PhilipsHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfStartsWith(ua,"Philips") || util.CheckIfStartsWith(ua,"PHILIPS")
-}
-
-type PortalmmmHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-
-func NewPortalmmmHandler(norm Normalizer) *PortalmmmHandler{
-	pmh := new(PortalmmmHandler)
-	pmh.Normalizer = norm
-	pmh.OrderedUAS = []string{}
-	pmh.UASWithDeviceId = make(map[string]string)
-	return pmh
-}
-
-func (h *PortalmmmHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *PortalmmmHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *PortalmmmHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *PortalmmmHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *PortalmmmHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-func (h *PortalmmmHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *PortalmmmHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *PortalmmmHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *PortalmmmHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *PortalmmmHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *PortalmmmHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *PortalmmmHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (pmh *PortalmmmHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfStartsWith(ua,"portalmmm")
-}
-
-func (pmh *PortalmmmHandler) ApplyConclusiveMatch(ua string) string {
-	return NO_MATCH
-}
-
-type  QtekHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-
-func NewQtekHandler(norm Normalizer) *QtekHandler {
-	qth := new(QtekHandler)
-	qth.Normalizer = norm
-	qth.OrderedUAS = []string{}
-	qth.UASWithDeviceId = make(map[string]string)
-	return qth
-}
-
-func (h *QtekHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *QtekHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *QtekHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *QtekHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *QtekHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *QtekHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *QtekHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *QtekHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *QtekHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *QtekHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *QtekHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *QtekHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *QtekHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (qth *QtekHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfStartsWith(ua,"Qtek")
-}
-
-
-type ReksioHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	ConstantIds []string
-}
-
-func NewReksioHandler(norm Normalizer) *ReksioHandler {
-	rkh := new(ReksioHandler)
-	rkh.Normalizer = norm
-	rkh.ConstantIds = []string{
-		"generic_reksio",
-	}
-	rkh.OrderedUAS = []string{}
-	rkh.UASWithDeviceId = make(map[string]string)
-	return rkh
-}
-
-func (h *ReksioHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *ReksioHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *ReksioHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *ReksioHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *ReksioHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-func (h *ReksioHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *ReksioHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *ReksioHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *ReksioHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *ReksioHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *ReksioHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *ReksioHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (rkh *ReksioHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-
-	return util.CheckIfStartsWith(ua,"Reksio")
-}
-
-func (rkh *ReksioHandler) ApplyConclusiveMatch(ua string) string {
-	return "generic_reksio"
-}
-
-type SPVHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-
-func NewSPVHandler(norm Normalizer) *SPVHandler {
-	sph := new(SPVHandler)
-	sph.Normalizer = norm
-	sph.OrderedUAS = []string{}
-	sph.UASWithDeviceId = make(map[string]string)
-	return sph
-}
-
-func (h *SPVHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *SPVHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *SPVHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *SPVHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *SPVHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-func (h *SPVHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *SPVHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *SPVHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *SPVHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *SPVHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *SPVHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *SPVHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (sph *SPVHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfContains(ua,"SPV")
-}
-
-func (sph *SPVHandler) ApplyConclusiveMatch(ua string) string {
-	tolerance := util.IndexOfOrLength(ua,";",strings.Index(ua,"SPV"))
-	return sph.GetDeviceIdFromRIS(ua,tolerance)
-}
-
-type SafariHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-
-func NewSafariHandler(norm Normalizer) *SafariHandler{
-	sh := new(SafariHandler)
-	sh.Normalizer = norm
-	sh.OrderedUAS = []string{}
-	sh.UASWithDeviceId = make(map[string]string)
-	return  sh
-}
-
-func (h *SafariHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *SafariHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *SafariHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *SafariHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *SafariHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *SafariHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *SafariHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *SafariHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *SafariHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *SafariHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *SafariHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *SafariHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *SafariHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (sh *SafariHandler) CanHandle(ua string) bool {
-	if util.IsMobileBrowser(ua){
-		return false
-	}
-	return util.CheckIfStartsWith(ua,"Mozilla") && util.CheckIfContains(ua,"Safari")
-}
-
-type SagemHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-
-func NewSagemHandler(norm Normalizer) *SagemHandler {
-	sgh := new(SagemHandler)
-	sgh.Normalizer = norm
-	sgh.OrderedUAS = []string{}
-	sgh.UASWithDeviceId = make(map[string]string)
-	return sgh
-}
-
-func (h *SagemHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *SagemHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *SagemHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *SagemHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *SagemHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *SagemHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *SagemHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *SagemHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *SagemHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *SagemHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *SagemHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *SagemHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *SagemHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (sgh *SagemHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfStartsWithAnyOf(ua,[]string{"Sagem","SAGEM"})
-}
-
-type SamsungHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
-
-func NewSamsungHandler(norm Normalizer) *SamsungHandler {
-	sgh := new(SamsungHandler)
-	sgh.Normalizer = norm
-	sgh.OrderedUAS = []string{}
-	sgh.UASWithDeviceId = make(map[string]string)
-	return sgh
-}
-
-func (h *SamsungHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *SamsungHandler) GetOrderedUAS() []string{
-	//fmt.Println(len(h.OrderedUAS))
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	//fmt.Println(h.OrderedUAS)
-	return h.OrderedUAS
-}
-
-func(h *SamsungHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	//fmt.Println(h.UASWithDeviceId)
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *SamsungHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	//fmt.Println("Match here:",match)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *SamsungHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || (len(strings.Trim(deviceId," ")) == 0)
-}
-
-func (h *SamsungHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *SamsungHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *SamsungHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *SamsungHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *SamsungHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		//fmt.Println("Samsung Can Handle")
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *SamsungHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (sgh *SamsungHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfContainsAnyOf(ua,[]string{"Samsung","SAMSUNG"}) || util.CheckIfStartsWithAnyOf(ua,[]string{"SEC-","SPH","SGH","SCH"})
-}
-
-func (sgh *SamsungHandler) ApplyConclusiveMatch(ua string) string{
-	var tolerance int
-	if util.CheckIfStartsWithAnyOf(ua,[]string{"SEC-", "SAMSUNG-", "SCH"}){
-		tolerance = util.FirstSlash(ua)
-	} else if util.CheckIfStartsWithAnyOf(ua,[]string{"Samsung", "SPH", "SGH"}){
-		tolerance = util.FirstSpace(ua)
-	} else {
-		tolerance = util.SecondSlash(ua)
-	}
-	return sgh.GetDeviceIdFromRIS(ua,tolerance)
-
+func (kh *KindleHandler) CanHandle(ua string) bool {
+	return kh.util.CheckIfContainsAnyOf(ua,[]string{"Kindle","Silk"})
 }
 
-func (sgh *SamsungHandler) ApplyRecoveryMatch(ua string) string {
-	//fmt.Println("applying recovery match for Samsung",ua)
+func (kh *KindleHandler) ApplyConclusiveMatch(ua string) string {
+	search := "Kindle/"
+	idx := strings.Index(ua,search)
 	var tolerance int
-	if util.CheckIfStartsWith(ua,"SAMSUNG"){
-		tolerance = 8
-		return sgh.GetDeviceIdFromLD(ua,tolerance)
-	} else {
-		idx := strings.Index(ua,"Samsung")
-		if idx == -1{
-			idx = 0
-		}
-		tolerance = util.IndexOfOrLength(ua,"/",idx)
-		return sgh.GetDeviceIdFromRIS(ua,tolerance)
+	if idx != -1 && idx + len(search) + 1 < len(ua) {
+        tolerance = idx + len(search) + 1
+        kindleVersion := ua[tolerance]
+        // RIS match only Kindle/1-3
+        if kindleVersion >= 1 && kindleVersion <= 3{
+            return kh.GetDeviceIdFromRIS(ua, tolerance)
+        }
 	}
-}
-
-
-type SanyoHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}
+	delimiterIdx := strings.Index(ua,RIS_DELIMITER)
+	if delimiterIdx != -1 {
+		tolerance = delimiterIdx + len(RIS_DELIMITER)
+		return kh.GetDeviceIdFromRIS(ua,tolerance)
+	}
+	return NO_MATCH
 
-func NewSanyoHandler(norm Normalizer) *SanyoHandler{
-	snh := new(SanyoHandler)
-	snh.Normalizer = norm
-	snh.OrderedUAS = []string{}
-	snh.UASWithDeviceId = make(map[string]string)
-	return snh
 }
 
-func (h *SanyoHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *SanyoHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
+func (kh *KindleHandler) ApplyRecoveryMatch(ua string)string {
+	if kh.util.CheckIfContains(ua,"Kindle/1"){
+		return "amazon_kindle_ver1"
 	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
+	if kh.util.CheckIfContains(ua,"Kindle/2"){
+		return "amazon_kindle2_ver1"
 	}
-	if mobile{
-		return GENERIC_MOBILE
+	if kh.util.CheckIfContains(ua, "Kindle/3"){
+		return "amazon_kindle3_ver1"
 	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
+	if kh.util.CheckIfContainsAnyOf(ua,[]string{"Kindle Fire","Silk"}){
+		return "amazon_kindle_fire_ver1"
 	}
-	return GENERIC
+	return "generic_amazon_kindle"
 }
 
-func (h *SanyoHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
+type KonquerorHandler struct{
+	BaseHandler
 }
 
-func(h *SanyoHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
+func NewKonquerorHandler(norm Normalizer) *KonquerorHandler{
+	kqh := new(KonquerorHandler)
+	kqh.Init(kqh, norm)
+	return kqh
 }
-func(h *SanyoHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+
+func (kqh *KonquerorHandler) CanHandle(ua string) bool {
+	if kqh.util.IsMobileBrowser(ua){
+		return false
 	}
-	return NO_MATCH
+	return kqh.util.CheckIfContains(ua,"Konqueror")
 }
 
-
-func (h *SanyoHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
+type KyoceraHandler struct{
+	BaseHandler
 }
 
-func (h *SanyoHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
+func NewKyoceraHandler(norm Normalizer) *KyoceraHandler{
+	kyh := new(KyoceraHandler)
+	kyh.Init(kyh, norm)
+	return kyh
 }
 
-func (h *SanyoHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
+func (kyh *KyoceraHandler) CanHandle(ua string) bool {
+	if kyh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return NO_MATCH
+	return kyh.util.CheckIfStartsWithAnyOf(ua,[]string{"kyocera", "QC-", "KWC-"})
 }
 
-func (h *SanyoHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
+type LGHandler struct{
+	BaseHandler
 }
 
-func (h *SanyoHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
+func NewLGHandler(norm Normalizer) *LGHandler{
+	lgh := new(LGHandler)
+	lgh.Init(lgh, norm)
+	return lgh
+}
+
+func (lgh *LGHandler) CanHandle(ua string) bool {
+	if lgh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return deviceId
+	return lgh.util.CheckIfContainsAnyOf(ua,[]string{"lg","LG"})
 }
 
-func (h *SanyoHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
+func (lgh *LGHandler) Tolerance(ua string) int{
+	return lgh.util.IndexOfOrLength(ua,"/",strings.Index(strings.ToUpper(ua),"LG"))
 }
 
-func (h *SanyoHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
+func (lgh *LGHandler) ApplyRecoveryMatch(ua string) string {
+	return lgh.GetDeviceIdFromRIS(ua,7)
 }
 
-func (snh *SanyoHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfStartsWithAnyOf(ua,[]string{"Sanyo","SANYO"}) || util.CheckIfContains(ua,"MobilePhone")
+type LGPLUSHandler struct{
+	BaseHandler
+	ConstantIds []string
+	lgPluses map[string][]string
 }
 
-func (snh *SanyoHandler) ApplyConclusiveMatch(ua string) string {
-	idx := strings.Index(ua, "MobilePhone")
-	var tolerance int
-	if idx != -1 {
-		tolerance = util.IndexOfOrLength("/",ua,idx)
-	} else {
-		tolerance = util.FirstSlash(ua)
+func NewLGPLUSHandler(norm Normalizer) *LGPLUSHandler{
+	lgph := new(LGPLUSHandler)
+	lgph.Init(lgph, norm)
+	lgph.ConstantIds = []string{
+		"generic_lguplus_rexos_facebook_browser",
+        "generic_lguplus_rexos_webviewer_browser",
+        "generic_lguplus_winmo_facebook_browser",
+        "generic_lguplus_android_webkit_browser",
+	}
+	lgph.lgPluses = map[string][]string{
+		"generic_lguplus_rexos_facebook_browser": []string{
+            "Windows NT 5",
+            "POLARIS",
+        },
+        "generic_lguplus_rexos_webviewer_browser": []string{
+            "Windows NT 5",
+        },
+        "generic_lguplus_winmo_facebook_browser": []string{
+            "Windows CE",
+            "POLARIS",
+        },
+        "generic_lguplus_android_webkit_browser": []string{
+            "Android",
+            "AppleWebKit",
+        },
 	}
-	return snh.GetDeviceIdFromRIS(ua,tolerance)
+	return lgph
 }
 
-type SharpHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+func (lgph *LGPLUSHandler) FixedIds() []string{
+	return lgph.ConstantIds
 }
 
-func NewSharpHandler(norm Normalizer) *SharpHandler{
-	shh := new(SharpHandler)
-	shh.Normalizer = norm
-	shh.OrderedUAS = []string{}
-	shh.UASWithDeviceId = make(map[string]string)
-	return shh
+func (lgph *LGPLUSHandler) CanHandle(ua string) bool {
+	if lgph.util.IsDesktopBrowser(ua){
+		return false
+	}
+	return lgph.util.CheckIfContainsAnyOf(ua,[]string{"LGUPLUS","lgtelecom"})
 }
 
-func (h *SharpHandler) ApplyRecoveryMatch(ua string) string{
+func (lgph *LGPLUSHandler) ApplyConclusiveMatch(ua string) string {
 	return NO_MATCH
 }
-func (h *SharpHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
+
+func (lgph *LGPLUSHandler) ApplyRecoveryMatch(ua string) string {
+	for deviceId, values := range lgph.lgPluses{
+		if lgph.util.CheckIfContainsAll(ua,values){
 			return deviceId
 		}
 	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
+	return NO_MATCH
 }
 
-func (h *SharpHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
+type MSIEHandler struct{
+	BaseHandler
+	ConstantIds []string
 }
 
-func(h *SharpHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+func NewMSIEHandler(norm Normalizer) *MSIEHandler{
+	msieh := new(MSIEHandler)
+	msieh.Init(msieh, norm)
+	msieh.ConstantIds = []string{
+		"msie",
+        "msie_4",
+        "msie_5",
+        "msie_5_5",
+        "msie_6",
+        "msie_7",
+        "msie_8",
+        "msie_9",
 	}
-	return NO_MATCH
+	return msieh
 }
-func(h *SharpHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+
+func (msieh *MSIEHandler) FixedIds() []string{
+	return msieh.ConstantIds
+}
+
+func (msieh *MSIEHandler) CanHandle(ua string) bool {
+	if msieh.util.IsMobileBrowser(ua){
+		return false
 	}
-	return NO_MATCH
+	if msieh.util.CheckIfContainsAnyOf(ua,[]string{"Opera", "armv", "MOTO", "BREW"}){
+		return false
+	}
+	return msieh.util.CheckIfStartsWith(ua,"Mozilla") && msieh.util.CheckIfContains(ua,"MSIE")
 }
 
-func (h *SharpHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
+var msieVersionRx = regexp.MustCompile(`^Mozilla\/4\.0 \(compatible; MSIE (\d)\.(\d);`)
+
+func (msieh *MSIEHandler) ApplyConclusiveMatch(ua string) string {
+	matches := msieVersionRx.FindStringSubmatch(ua)
+	if len(matches) > 0{
+		value,_ := strconv.Atoi(matches[1])
+		if value == 7{
+			return "msie_7"
+		} else if value == 8 {
+			return "msie_8"
+		} else if value == 9 {
+			return "msie_9"
+		} else if value == 6 {
+			return "msie_6"
+		} else if value == 5 {
+			if matches[2] == "5"{
+				return "msie_5_5"
+			}
+			return "msie_5"
+		} else {
+			return "msie"
+		}
 	}
-	return NO_MATCH
+	tolerance := msieh.util.FirstSlash(ua)
+	return msieh.GetDeviceIdFromRIS(ua,tolerance)
 }
 
-func (h *SharpHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
+type MitsubishiHandler struct{
+	BaseHandler
 }
 
-func (h *SharpHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
+func NewMitsubishiHandler(norm Normalizer) *MitsubishiHandler{
+	mh := new(MitsubishiHandler)
+	mh.Init(mh, norm)
+	return mh
 }
 
-func (h *SharpHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
+func (mh *MitsubishiHandler) CanHandle(ua string) bool {
+	if mh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return NO_MATCH
+	return mh.util.CheckIfStartsWith(ua,"Mitsu")
 }
 
-func (h *SharpHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
+func (mh *MitsubishiHandler) Tolerance(ua string) int{
+	return mh.util.FirstSpace(ua)
 }
 
-func (h *SharpHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
+type MotorolaHandler struct{
+	BaseHandler
+	ConstantIds []string
 }
 
-func (h *SharpHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
+func NewMotorolaHandler(norm Normalizer) *MotorolaHandler{
+	mh := new(MotorolaHandler)
+	mh.Init(mh, norm)
+	mh.ConstantIds = []string{
+		"mot_mib22_generic",
 	}
-	return
+	return mh
 }
 
-func (h *SharpHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
+func (mh *MotorolaHandler) FixedIds() []string{
+	return mh.ConstantIds
 }
 
-func (shh *SharpHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
+func (mh *MotorolaHandler) CanHandle(ua string) bool {
+	if mh.util.IsDesktopBrowser(ua){
 		return false
 	}
-	return util.CheckIfStartsWithAnyOf(ua,[]string{"Sharp", "SHARP"})
+	return mh.util.CheckIfStartsWithAnyOf(ua,[]string{"Mot-","MOT-", "MOTO", "moto"}) || mh.util.CheckIfContains(ua,"Motorola")
 }
 
+func (mh *MotorolaHandler) Tolerance(ua string) int{
+	if mh.util.CheckIfStartsWithAnyOf(ua, []string{"Mot-","MOT-", "Motorola"}){
+		return mh.util.FirstSlash(ua)
+	}
+	return 5
+}
 
-type SiemensHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-}	
+func (mh *MotorolaHandler) ApplyRecoveryMatch(ua string)string {
+	if mh.util.CheckIfContainsAnyOf(ua,[]string{"MIB/2.2","MIB/BER2.2"}){
+		return "mot_mib22_generic"
+	}
+	return NO_MATCH
+}
 
-func NewSiemensHandler(norm Normalizer) *SiemensHandler{
-	smh := new(SiemensHandler)
-	smh.Normalizer = norm
-	smh.OrderedUAS = []string{}
-	smh.UASWithDeviceId = make(map[string]string)
-	return smh
+type NecHandler struct{
+	BaseHandler
+	NecKgtTolerance int
 }
 
-func (h *SiemensHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
+func NewNecHandler(norm Normalizer) *NecHandler{
+	nh := new(NecHandler)
+	nh.Init(nh, norm)
+	nh.NecKgtTolerance = 2
+	return nh
 }
-func (h *SiemensHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
+
+func (nh *NecHandler) CanHandle(ua string) bool {
+	if nh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return GENERIC
+	return nh.util.CheckIfStartsWithAnyOf(ua,[]string{"NEC-","KGT"})
 }
 
-func (h *SiemensHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
+func (nh *NecHandler) Tolerance(ua string) int{
+	if nh.util.CheckIfStartsWith(ua,"NEC-1"){
+		return nh.util.FirstSlash(ua)
 	}
-	return h.OrderedUAS
+	return nh.NecKgtTolerance
 }
 
-func(h *SiemensHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
+type NintendoHandler struct{
+	BaseHandler
+	ConstantIds []string
 }
-func(h *SiemensHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+
+func NewNintendoHandler(norm Normalizer) *NintendoHandler{
+	nh := new(NintendoHandler)
+	nh.Init(nh, norm)
+	nh.ConstantIds = []string{
+		"nintendo_wii_ver1",
+        "nintendo_dsi_ver1",
+		"nintendo_ds_ver1",
 	}
-	return NO_MATCH
+	return nh
 }
 
-func (h *SiemensHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
+func (nh *NintendoHandler) FixedIds() []string{
+	return nh.ConstantIds
 }
 
-func (h *SiemensHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
+func (nh *NintendoHandler) CanHandle(ua string) bool {
+	if nh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return NO_MATCH
+	if nh.util.CheckIfContains(ua,"Nintendo"){
+		return true
+	}
+	return nh.util.CheckIfStartsWith(ua,"Mozilla") && nh.util.CheckIfContainsAll(ua,[]string{"Nitro", "Opera"})
 }
 
-func (h *SiemensHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
+func (nh *NintendoHandler) ApplyConclusiveMatch(ua string) string {
+	return nh.GetDeviceIdFromLD(ua,0)
 }
 
-func (h *SiemensHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
+func (nh *NintendoHandler) ApplyRecoveryMatch(ua string) string {
+	if nh.util.CheckIfContains(ua,"Nintendo Wii"){
+		return "nintendo_wii_ver1"
 	}
-	return NO_MATCH
+	if  nh.util.CheckIfContains(ua,"Nintendo DSi"){
+		return "nintendo_dsi_ver1"
+	}
+	if nh.util.CheckIfStartsWith(ua,"Mozilla") && nh.util.CheckIfContainsAll(ua,[]string{"Nitro", "Opera"}){
+		return "nintendo_ds_ver1"
+	}
+	return "nintendo_wii_ver1"
 }
 
-func (h *SiemensHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
+type NokiaHandler struct{
+	BaseHandler
+	ConstantIds []string
 }
 
-func (h *SiemensHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
+func NewNokiaHandler(norm Normalizer) *NokiaHandler{
+	nh := new(NokiaHandler)
+	nh.Init(nh, norm)
+	nh.ConstantIds = []string{
+		"nokia_generic_series60",
+		"nokia_generic_series80",
+		"nokia_generic_meego",
 	}
-	return deviceId
+	return nh
+}
+
+func (nh *NokiaHandler) FixedIds() []string{
+	return nh.ConstantIds
 }
 
-func (h *SiemensHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
+func (nh *NokiaHandler) CanHandle(ua string) bool {
+	if nh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return GENERIC
+	return nh.util.CheckIfContains(ua,"Nokia")
 }
 
-func (h *SiemensHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
+func (nh *NokiaHandler) ApplyRecoveryMatch(ua string) string {
+	if nh.util.CheckIfContains(ua,"Series60"){
+		return "nokia_generic_series60"
 	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
+	if nh.util.CheckIfContains(ua,"Series80"){
+		return "nokia_generic_series80"
 	}
-	return
-}
-
-func (smh *SiemensHandler) CanHandle(ua string) bool{
-	if util.IsDesktopBrowser(ua){
-		return false
+	if nh.util.CheckIfContains(ua,"MeeGo"){
+		return "nokia_generic_meego"
 	}
-	return util.CheckIfStartsWith(ua,"SIE-")
+	return NO_MATCH
 }
 
-type SmartTVHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+type NokiaOviBrowserHandler struct{
+	BaseHandler
 	ConstantIds []string
 }
 
-func NewSmartTVHandler(norm Normalizer) *SmartTVHandler{
-	smh := new(SmartTVHandler)
-	smh.ConstantIds = []string{
-		"generic_smarttv_browser",
-        "generic_smarttv_googletv_browser",
-        "generic_smarttv_appletv_browser",
-        "generic_smarttv_boxeebox_browser",
+func NewNokiaOviBrowserHandler(norm Normalizer) *NokiaOviBrowserHandler{
+	novih := new(NokiaOviBrowserHandler)
+	novih.Init(novih, norm)
+	novih.ConstantIds = []string{
+		"nokia_generic_series40_ovibrosr",
 	}
-	smh.Normalizer = norm
-	smh.OrderedUAS = []string{}
-	smh.UASWithDeviceId = make(map[string]string)
-	return smh
+	return novih
 }
 
-func (h *SmartTVHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
+func (novih *NokiaOviBrowserHandler) FixedIds() []string{
+	return novih.ConstantIds
 }
 
-func (h *SmartTVHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
+func (novih *NokiaOviBrowserHandler) CanHandle(ua string) bool {
+	if novih.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return h.OrderedUAS
+	return novih.util.CheckIfContains(ua,"S40OviBrowser")
 }
 
-func(h *SmartTVHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *SmartTVHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+func (novih *NokiaOviBrowserHandler) ApplyConclusiveMatch(ua string) string {
+	idx := strings.Index(ua,"Nokia")
+	if idx == -1 {
+		return NO_MATCH
 	}
-	return NO_MATCH
+	tolerance := novih.util.IndexOfAnyOrLength(ua,[]string{"/"," "},idx)
+	return novih.GetDeviceIdFromRIS(ua,tolerance)
 }
 
-
-func (h *SmartTVHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
+type OperaHandler struct{
+	BaseHandler
+	ConstantIds []string
 }
 
-func (h *SmartTVHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
+func NewOperaHandler(norm Normalizer) *OperaHandler{
+	oh := new(OperaHandler)
+	oh.Init(oh, norm)
+	oh.ConstantIds = []string{
+		"opera",
+        "opera_7",
+        "opera_8",
+        "opera_9",
+        "opera_10",
+        "opera_11",
+		"opera_12",
 	}
-	return NO_MATCH
+	return oh
 }
 
-func (h *SmartTVHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
+func (oh *OperaHandler) FixedIds() []string{
+	return oh.ConstantIds
 }
 
-func (h *SmartTVHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
+func (oh *OperaHandler) CanHandle(ua string) bool {
+	if oh.util.IsMobileBrowser(ua){
+		return false
 	}
-	return deviceId
+	return oh.util.CheckIfContains(ua,"Opera")
 }
 
-func (h *SmartTVHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
+func (oh *OperaHandler) Tolerance(ua string) int{
+	opIdx := strings.Index(ua,"Opera")
+	return oh.util.IndexOfOrLength(ua,".",opIdx)
 }
 
-func (h *SmartTVHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
+func (oh *OperaHandler) ApplyRecoveryMatch(ua string) string {
+	operaVersion := oh.GetOperaVersion(ua)
+	if operaVersion == 0.0{
+		return "opera"
 	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
+	MajorVersion := math.Floor(operaVersion)
+	
+	strVer:= strconv.FormatFloat(MajorVersion,[]byte("f")[0],1,64)
+	id := "opera_" + strVer
+	for k := range oh.ConstantIds{
+		if oh.ConstantIds[k] == id{
+			return id
+		}
 	}
-	return GENERIC
+	return "opera"
 }
 
-func (h *SmartTVHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
+var operaPrestoVersionRx = regexp.MustCompile(`Opera[ /]?(\d+\.\d+)`)
 
-func (smh *SmartTVHandler) CanHandle(ua string) bool {
-	return util.IsSmartTV(ua)
+func (oh *OperaHandler) GetOperaVersion(ua string) float64 {
+	matches := operaPrestoVersionRx.FindStringSubmatch(ua)
+	var ver float64
+	if len(matches) > 0{
+		ver,_=strconv.ParseFloat(matches[1],64)
+		return ver
+	}
+	return float64(0.0)
 }
 
-func (smh *SmartTVHandler) ApplyConclusiveMatch(ua string) string {
-	tolerance := len(ua)
-	return smh.GetDeviceIdFromRIS(ua,tolerance)
+type OperaMiniHandler struct{
+	BaseHandler
+	operaMinis map[string]string
 }
 
-func (smh *SmartTVHandler) ApplyRecoveryMatch(ua string) string {
-	if util.CheckIfContains(ua,"SmartTV"){
-		return "generic_smarttv_browser"
-	}
-	if util.CheckIfContains(ua,"GoogleTV"){
-		return "generic_smarttv_googletv_browser"
-	}
-	if util.CheckIfContains(ua,"AppleTV"){
-		return "generic_smarttv_appletv_browser"
-	}
-	if util.CheckIfContains(ua,"Boxee"){
-		return "generic_smarttv_boxeebox_browser"
+func NewOperaMiniHandler(norm Normalizer) *OperaMiniHandler{
+	omh := new(OperaMiniHandler)
+	omh.Init(omh, norm)
+	omh.operaMinis = map[string]string{
+		"Opera Mini/1": "generic_opera_mini_version1",
+        "Opera Mini/2": "generic_opera_mini_version2",
+        "Opera Mini/3": "generic_opera_mini_version3",
+        "Opera Mini/4": "generic_opera_mini_version4",
+        "Opera Mini/5": "generic_opera_mini_version5",
 	}
-	return "generic_smarttv_browser"
-}
-
-type SonyEricssonHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+	return omh
 }
 
-func NewSonyEricssonHandler(norm Normalizer) *SonyEricssonHandler{
-	seh := new(SonyEricssonHandler)
-	seh.Normalizer = norm
-	seh.OrderedUAS = []string{}
-	seh.UASWithDeviceId = make(map[string]string)
-	return seh
+func (omh *OperaMiniHandler) CanHandle(ua string) bool {
+	return omh.util.CheckIfContains(ua,"Opera Mini")
 }
 
-func (h *SonyEricssonHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *SonyEricssonHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
+func (omh *OperaMiniHandler) ApplyRecoveryMatch(ua string) string {
+	for key,deviceId := range omh.operaMinis{
+		if omh.util.CheckIfContains(ua,key){
 			return deviceId
 		}
 	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
+	if omh.util.CheckIfContains(ua,"Opera Mobi"){
+		return "generic_opera_mini_version4"
 	}
-	return GENERIC
+	return "generic_opera_mini_version1"
 }
 
-func (h *SonyEricssonHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
+type PanasonicHandler struct{
+	BaseHandler
 }
 
-func(h *SonyEricssonHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
+func NewPanasonicHandler(norm Normalizer) *PanasonicHandler {
+	ph := new(PanasonicHandler)
+	ph.Init(ph, norm)
+	return ph
 }
-func(h *SonyEricssonHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+
+func (ph *PanasonicHandler) CanHandle(ua string) bool {
+	if ph.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return NO_MATCH
+	return ph.util.CheckIfStartsWith(ua,"Panasonic")
 }
 
-func (h *SonyEricssonHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
+type PantechHandler struct{
+	BaseHandler
+	PantechTolerance int
 }
 
-func (h *SonyEricssonHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
+func NewPantechHandler(norm Normalizer) *PantechHandler{
+	ph := new(PantechHandler)
+	ph.Init(ph, norm)
+	ph.PantechTolerance = 5
+	return ph
 }
 
-func (h *SonyEricssonHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
+func (ph *PantechHandler) CanHandle(ua string) bool {
+	if ph.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return NO_MATCH
+	return ph.util.CheckIfStartsWithAnyOf(ua,[]string{"Pantech","PT-","PANTECH","PG-"})
 }
 
-func (h *SonyEricssonHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
+func (ph *PantechHandler) Tolerance(ua string) int{
+	var tolerance int
+	if ph.util.CheckIfStartsWith(ua,"Pantech"){
+		tolerance = ph.PantechTolerance
+	} else {
+		tolerance = ph.util.FirstSlash(ua)
 	}
-	return
+	return tolerance
 }
 
-func (h *SonyEricssonHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
+type PhilipsHandler struct{
+	BaseHandler
 }
 
-func (h *SonyEricssonHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
+func NewPhilipsHandler(norm Normalizer) *PhilipsHandler{
+	ph := new(PhilipsHandler)
+	ph.Init(ph, norm)
+	return ph
+}
+
+func (ph *PhilipsHandler) CanHandle(ua string) bool {
+	if ph.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return GENERIC
+	return ph.util.CheckIfStartsWith(ua,"Philips") || ph.util.CheckIfStartsWith(ua,"PHILIPS")
 }
 
-func (h *SonyEricssonHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
+type PortalmmmHandler struct{
+	BaseHandler
 }
 
-func (seh *SonyEricssonHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
-		return false
-	}
-	return util.CheckIfContains(ua,"Sony")
+func NewPortalmmmHandler(norm Normalizer) *PortalmmmHandler{
+	pmh := new(PortalmmmHandler)
+	pmh.Init(pmh, norm)
+	return pmh
 }
 
-func (seh *SonyEricssonHandler) ApplyConclusiveMatch(ua string) string{
-	var tolerance int
-	if util.CheckIfStartsWith(ua, "SonyEricsson"){
-		tolerance = util.FirstSlash(ua) - 1
-		return seh.GetDeviceIdFromRIS(ua,tolerance)
+func (pmh *PortalmmmHandler) CanHandle(ua string) bool {
+	if pmh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	tolerance = util.SecondSlash(ua)
-	return seh.GetDeviceIdFromRIS(ua,tolerance)
+	return pmh.util.CheckIfStartsWith(ua,"portalmmm")
 }
 
-
-type ToshibaHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+func (pmh *PortalmmmHandler) ApplyConclusiveMatch(ua string) string {
+	return NO_MATCH
 }
 
-func NewToshibaHandler(norm Normalizer) *ToshibaHandler{
-	th := new(ToshibaHandler)
-	th.Normalizer = norm
-	th.OrderedUAS = []string{}
-	th.UASWithDeviceId = make(map[string]string)
-	return th
+type  QtekHandler struct{
+	BaseHandler
 }
 
-func (h *ToshibaHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
+func NewQtekHandler(norm Normalizer) *QtekHandler {
+	qth := new(QtekHandler)
+	qth.Init(qth, norm)
+	return qth
 }
-func (h *ToshibaHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
+
+func (qth *QtekHandler) CanHandle(ua string) bool {
+	if qth.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return GENERIC
+	return qth.util.CheckIfStartsWith(ua,"Qtek")
 }
 
-func (h *ToshibaHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
+type ReksioHandler struct{
+	BaseHandler
+	ConstantIds []string
 }
 
-func(h *ToshibaHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+func NewReksioHandler(norm Normalizer) *ReksioHandler {
+	rkh := new(ReksioHandler)
+	rkh.Init(rkh, norm)
+	rkh.ConstantIds = []string{
+		"generic_reksio",
 	}
-	return NO_MATCH
+	return rkh
 }
-func(h *ToshibaHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
+
+func (rkh *ReksioHandler) FixedIds() []string{
+	return rkh.ConstantIds
 }
 
-func (h *ToshibaHandler) ApplyConclusiveMatch(ua string) string{
-	match := h.LookForMatchingUA(ua)
-	if len(match) > 0{
-		return h.UASWithDeviceId[match]
+func (rkh *ReksioHandler) CanHandle(ua string) bool {
+	if rkh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return NO_MATCH
+
+	return rkh.util.CheckIfStartsWith(ua,"Reksio")
 }
 
-func (h *ToshibaHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
+func (rkh *ReksioHandler) ApplyConclusiveMatch(ua string) string {
+	return "generic_reksio"
 }
 
-func (h *ToshibaHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
+type SPVHandler struct{
+	BaseHandler
 }
 
-func (h *ToshibaHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
+func NewSPVHandler(norm Normalizer) *SPVHandler {
+	sph := new(SPVHandler)
+	sph.Init(sph, norm)
+	return sph
 }
 
-func (h *ToshibaHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
+func (sph *SPVHandler) CanHandle(ua string) bool {
+	if sph.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return GENERIC
+	return sph.util.CheckIfContains(ua,"SPV")
 }
 
-func (h *ToshibaHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
+func (sph *SPVHandler) Tolerance(ua string) int{
+	return sph.util.IndexOfOrLength(ua,";",strings.Index(ua,"SPV"))
 }
 
-func (h *ToshibaHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
+type SafariHandler struct{
+	BaseHandler
 }
 
-func (h *ToshibaHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
+func NewSafariHandler(norm Normalizer) *SafariHandler{
+	sh := new(SafariHandler)
+	sh.Init(sh, norm)
+	return  sh
+}
+
+func (sh *SafariHandler) CanHandle(ua string) bool {
+	if sh.util.IsMobileBrowser(ua){
+		return false
 	}
-	return
+	return sh.util.CheckIfStartsWith(ua,"Mozilla") && sh.util.CheckIfContains(ua,"Safari")
+}
+
+type SagemHandler struct{
+	BaseHandler
+}
+
+func NewSagemHandler(norm Normalizer) *SagemHandler {
+	sgh := new(SagemHandler)
+	sgh.Init(sgh, norm)
+	return sgh
 }
 
-func (th *ToshibaHandler) CanHandle(ua string) bool{
-	if util.IsDesktopBrowser(ua){
+func (sgh *SagemHandler) CanHandle(ua string) bool {
+	if sgh.util.IsDesktopBrowser(ua){
 		return false
 	}
-	return util.CheckIfStartsWith(ua,"Toshiba")
+	return sgh.util.CheckIfStartsWithAnyOf(ua,[]string{"Sagem","SAGEM"})
 }
 
-type VodafoneHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+type SamsungHandler struct{
+	BaseHandler
 }
 
-func NewVodafoneHandler(norm Normalizer) *VodafoneHandler{
-	vh := new(VodafoneHandler)
-	vh.Normalizer = norm
-	vh.OrderedUAS = []string{}
-	vh.UASWithDeviceId = make(map[string]string)
-	return vh
+func NewSamsungHandler(norm Normalizer) *SamsungHandler {
+	sgh := new(SamsungHandler)
+	sgh.Init(sgh, norm)
+	return sgh
 }
 
-func (h *VodafoneHandler) ApplyRecoveryMatch(ua string) string{
-	return NO_MATCH
-}
-func (h *VodafoneHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
+func (sgh *SamsungHandler) CanHandle(ua string) bool {
+	if sgh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return GENERIC
+	return sgh.util.CheckIfContainsAnyOf(ua,[]string{"Samsung","SAMSUNG"}) || sgh.util.CheckIfStartsWithAnyOf(ua,[]string{"SEC-","SPH","SGH","SCH"})
 }
 
-func (h *VodafoneHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
+func (sgh *SamsungHandler) Tolerance(ua string) int{
+	var tolerance int
+	if sgh.util.CheckIfStartsWithAnyOf(ua,[]string{"SEC-", "SAMSUNG-", "SCH"}){
+		tolerance = sgh.util.FirstSlash(ua)
+	} else if sgh.util.CheckIfStartsWithAnyOf(ua,[]string{"Samsung", "SPH", "SGH"}){
+		tolerance = sgh.util.FirstSpace(ua)
+	} else {
+		tolerance = sgh.util.SecondSlash(ua)
 	}
-	return h.OrderedUAS
-}
+	return tolerance
 
-func(h *VodafoneHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
 }
-func(h *VodafoneHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+
+func (sgh *SamsungHandler) ApplyRecoveryMatch(ua string) string {
+	//fmt.Println("applying recovery match for Samsung",ua)
+	var tolerance int
+	if sgh.util.CheckIfStartsWith(ua,"SAMSUNG"){
+		tolerance = 8
+		return sgh.GetDeviceIdFromLD(ua,tolerance)
+	} else {
+		idx := strings.Index(ua,"Samsung")
+		if idx == -1{
+			idx = 0
+		}
+		tolerance = sgh.util.IndexOfOrLength(ua,"/",idx)
+		return sgh.GetDeviceIdFromRIS(ua,tolerance)
 	}
-	return NO_MATCH
 }
 
-func (h *VodafoneHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
+type SanyoHandler struct{
+	BaseHandler
 }
 
-func (h *VodafoneHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
+func NewSanyoHandler(norm Normalizer) *SanyoHandler{
+	snh := new(SanyoHandler)
+	snh.Init(snh, norm)
+	return snh
 }
 
-func (h *VodafoneHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
+func (snh *SanyoHandler) CanHandle(ua string) bool {
+	if snh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return NO_MATCH
+	return snh.util.CheckIfStartsWithAnyOf(ua,[]string{"Sanyo","SANYO"}) || snh.util.CheckIfContains(ua,"MobilePhone")
 }
 
-func (h *VodafoneHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
+func (snh *SanyoHandler) Tolerance(ua string) int{
+	idx := strings.Index(ua, "MobilePhone")
+	var tolerance int
+	if idx != -1 {
+		tolerance = snh.util.IndexOfOrLength(ua,"/",idx)
+	} else {
+		tolerance = snh.util.FirstSlash(ua)
 	}
-	return deviceId
+	return tolerance
 }
 
-func (h *VodafoneHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
+type SharpHandler struct{
+	BaseHandler
 }
 
-func (h *VodafoneHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
+func NewSharpHandler(norm Normalizer) *SharpHandler{
+	shh := new(SharpHandler)
+	shh.Init(shh, norm)
+	return shh
 }
 
-func (h *VodafoneHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
+func (shh *SharpHandler) CanHandle(ua string) bool {
+	if shh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return
+	return shh.util.CheckIfStartsWithAnyOf(ua,[]string{"Sharp", "SHARP"})
 }
 
-func (vh *VodafoneHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
+type SiemensHandler struct{
+	BaseHandler
+}	
+
+func NewSiemensHandler(norm Normalizer) *SiemensHandler{
+	smh := new(SiemensHandler)
+	smh.Init(smh, norm)
+	return smh
+}
+
+func (smh *SiemensHandler) CanHandle(ua string) bool{
+	if smh.util.IsDesktopBrowser(ua){
 		return false
 	}
-	return util.CheckIfStartsWith(ua,"Vodafone")
+	return smh.util.CheckIfStartsWith(ua,"SIE-")
 }
 
-func (vh *VodafoneHandler) ApplyConclusiveMatch(ua string) string {
-	tolerance := util.FirstSlash(ua)
-	return vh.GetDeviceIdFromRIS(ua,tolerance)
+type SmartTVHandler struct{
+	BaseHandler
+	ConstantIds []string
 }
 
+func NewSmartTVHandler(norm Normalizer) *SmartTVHandler{
+	smh := new(SmartTVHandler)
+	smh.Init(smh, norm)
+	smh.ConstantIds = []string{
+		"generic_smarttv_browser",
+        "generic_smarttv_googletv_browser",
+        "generic_smarttv_appletv_browser",
+        "generic_smarttv_boxeebox_browser",
+	}
+	return smh
+}
 
+func (smh *SmartTVHandler) FixedIds() []string{
+	return smh.ConstantIds
+}
 
-type WebOSHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
-	ConstantIds []string
+func (smh *SmartTVHandler) CanHandle(ua string) bool {
+	return smh.util.IsSmartTV(ua)
 }
 
-func NewWebOSHandler(norm Normalizer) *WebOSHandler{
-	webOsHandler := new(WebOSHandler)
-	webOsHandler.ConstantIds = []string{
-		"hp_tablet_webos_generic",
-		"hp_webos_generic",
-	}
-	webOsHandler.Normalizer = norm
-	webOsHandler.OrderedUAS = []string{}
-	webOsHandler.UASWithDeviceId = make(map[string]string)
-	return webOsHandler
+func (smh *SmartTVHandler) Tolerance(ua string) int{
+	return len(ua)
 }
 
-func (h *WebOSHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
+func (smh *SmartTVHandler) ApplyRecoveryMatch(ua string) string {
+	if smh.util.CheckIfContains(ua,"SmartTV"){
+		return "generic_smarttv_browser"
 	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
+	if smh.util.CheckIfContains(ua,"GoogleTV"){
+		return "generic_smarttv_googletv_browser"
 	}
-	if mobile{
-		return GENERIC_MOBILE
+	if smh.util.CheckIfContains(ua,"AppleTV"){
+		return "generic_smarttv_appletv_browser"
 	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
+	if smh.util.CheckIfContains(ua,"Boxee"){
+		return "generic_smarttv_boxeebox_browser"
 	}
-	return GENERIC
+	return "generic_smarttv_browser"
 }
 
-func (h *WebOSHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
+type SonyEricssonHandler struct{
+	BaseHandler
 }
 
-func(h *WebOSHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+func NewSonyEricssonHandler(norm Normalizer) *SonyEricssonHandler{
+	seh := new(SonyEricssonHandler)
+	seh.Init(seh, norm)
+	return seh
+}
+
+func (seh *SonyEricssonHandler) CanHandle(ua string) bool {
+	if seh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return NO_MATCH
+	return seh.util.CheckIfContains(ua,"Sony")
 }
-func(h *WebOSHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
+
+func (seh *SonyEricssonHandler) Tolerance(ua string) int{
+	var tolerance int
+	if seh.util.CheckIfStartsWith(ua, "SonyEricsson"){
+		tolerance = seh.util.FirstSlash(ua) - 1
+		return tolerance
 	}
-	return NO_MATCH
+	tolerance = seh.util.SecondSlash(ua)
+	return tolerance
 }
 
-func (h *WebOSHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
+type ToshibaHandler struct{
+	BaseHandler
 }
 
-func (h *WebOSHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
+func NewToshibaHandler(norm Normalizer) *ToshibaHandler{
+	th := new(ToshibaHandler)
+	th.Init(th, norm)
+	return th
+}
+
+func (th *ToshibaHandler) CanHandle(ua string) bool{
+	if th.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return NO_MATCH
+	return th.util.CheckIfStartsWith(ua,"Toshiba")
 }
 
-func (h *WebOSHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
+type VodafoneHandler struct{
+	BaseHandler
 }
 
-func (h *WebOSHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
+func NewVodafoneHandler(norm Normalizer) *VodafoneHandler{
+	vh := new(VodafoneHandler)
+	vh.Init(vh, norm)
+	return vh
+}
+
+func (vh *VodafoneHandler) CanHandle(ua string) bool {
+	if vh.util.IsDesktopBrowser(ua){
+		return false
 	}
-	return deviceId
+	return vh.util.CheckIfStartsWith(ua,"Vodafone")
 }
 
-func (h *WebOSHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
+type WebOSHandler struct{
+	BaseHandler
+	ConstantIds []string
 }
 
-func (h *WebOSHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
+func NewWebOSHandler(norm Normalizer) *WebOSHandler{
+	webOsHandler := new(WebOSHandler)
+	webOsHandler.Init(webOsHandler, norm)
+	webOsHandler.ConstantIds = []string{
+		"hp_tablet_webos_generic",
+		"hp_webos_generic",
 	}
-	return GENERIC
+	return webOsHandler
 }
 
-func (h *WebOSHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
+func (wh *WebOSHandler) FixedIds() []string{
+	return wh.ConstantIds
 }
 
 func (wh *WebOSHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
+	if wh.util.IsDesktopBrowser(ua){
 		return false
 	}
-	return util.CheckIfContainsAnyOf(ua,[]string{"webOS","hpwOS"})
+	return wh.util.CheckIfContainsAnyOf(ua,[]string{"webOS","hpwOS"})
 }
 
 func (wh *WebOSHandler) ApplyConclusiveMatch(ua string)string {
@@ -7546,15 +2203,19 @@ func (wh *WebOSHandler) ApplyConclusiveMatch(ua string)string {
 }
 
 func (wh *WebOSHandler) ApplyRecoveryMatch(ua string) string {
-	if util.CheckIfContains(ua,"hpwOS/3"){
+	if wh.util.CheckIfContains(ua,"hpwOS/3"){
 		return "hp_tablet_webos_generic"
 	}
 	return "hp_webos_generic"
 }
 
+var (
+	webOSModelVersionRx = regexp.MustCompile(` ([^/]+)/([\d\.]+)$`)
+	webOSVersionRx = regexp.MustCompile(`(?:hpw|web)OS.(\d)\.`)
+)
+
 func (wh *WebOSHandler) GetWebOSModelVersion(ua string) string{
-	wordRx := regexp.MustCompile(` ([^/]+)/([\d\.]+)$`)
-	matches := wordRx.FindStringSubmatch(ua)
+	matches := webOSModelVersionRx.FindStringSubmatch(ua)
 	if len(matches) > 0{
 		return matches[1] + " " + matches[2]
 	}
@@ -7562,8 +2223,7 @@ func (wh *WebOSHandler) GetWebOSModelVersion(ua string) string{
 }
 
 func (wh *WebOSHandler) GetWebOSVersion(ua string) string{
-	wordRx := regexp.MustCompile(`(?:hpw|web)OS.(\d)\.`)
-	matches := wordRx.FindStringSubmatch(ua)
+	matches := webOSVersionRx.FindStringSubmatch(ua)
 	if len(matches) > 0{
 		return "webOS" + matches[1]
 	}
@@ -7571,137 +2231,26 @@ func (wh *WebOSHandler) GetWebOSVersion(ua string) string{
 }
 
 type WindowsPhoneDesktopHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+	BaseHandler
 	ConstantIds []string
 }
 
 func NewWindowsPhoneDesktopHandler(norm Normalizer) *WindowsPhoneDesktopHandler{
 	wph := new(WindowsPhoneDesktopHandler)
-	wph.Normalizer = norm
+	wph.Init(wph, norm)
 	wph.ConstantIds = []string{
 		"generic_ms_phone_os7_desktopmode",
         "generic_ms_phone_os7_5_desktopmode",
 	}
-	wph.OrderedUAS = []string{}
-	wph.UASWithDeviceId = make(map[string]string)
 	return wph
 }
 
-
-func (h *WindowsPhoneDesktopHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *WindowsPhoneDesktopHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *WindowsPhoneDesktopHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *WindowsPhoneDesktopHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-func (h *WindowsPhoneDesktopHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *WindowsPhoneDesktopHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *WindowsPhoneDesktopHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *WindowsPhoneDesktopHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *WindowsPhoneDesktopHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *WindowsPhoneDesktopHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
-}
-
-func (h *WindowsPhoneDesktopHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
+func (wph *WindowsPhoneDesktopHandler) FixedIds() []string{
+	return wph.ConstantIds
 }
 
 func (wph *WindowsPhoneDesktopHandler) CanHandle(ua string) bool {
-	return util.CheckIfContains(ua,"ZuneWP7")
+	return wph.util.CheckIfContains(ua,"ZuneWP7")
 }
 
 func (wph *WindowsPhoneDesktopHandler) ApplyConclusiveMatch(ua string) string {
@@ -7709,148 +2258,37 @@ func (wph *WindowsPhoneDesktopHandler) ApplyConclusiveMatch(ua string) string {
 }
 
 func (wph *WindowsPhoneDesktopHandler) ApplyRecoveryMatch(ua string)string {
-	if util.CheckIfContains(ua,"Trident/5.0"){
+	if wph.util.CheckIfContains(ua,"Trident/5.0"){
 		return "generic_ms_phone_os7_5_desktopmode"
 	}
 	return "generic_ms_phone_os7_desktopmode"
 }
 
 type WindowsPhoneHandler struct{
-	OrderedUAS []string
-	Normalizer Normalizer
-	UASWithDeviceId map[string]string
-	nextHandler Handlers
+	BaseHandler
 	ConstantIds []string
 }
 
 func NewWindowsPhoneHandler(norm Normalizer) *WindowsPhoneHandler{
 	wph := new(WindowsPhoneHandler)
+	wph.Init(wph, norm)
 	wph.ConstantIds = []string{
 		"generic_ms_winmo6_5",
         "generic_ms_phone_os7",
         "generic_ms_phone_os7_5",
 	}
-	wph.Normalizer = norm
-	wph.OrderedUAS = []string{}
-	wph.UASWithDeviceId = make(map[string]string)
 	return wph
 }
 
-func (h *WindowsPhoneHandler) ApplyRecoveryCatchAllMatch(ua string) string{
-	if util.IsDesktopBrowserHeavyDutyAnalysis(ua){
-		return GENERIC_WEB_BROWSER
-	}
-	mobile := util.IsMobileBrowser(ua)
-	desktop := util.IsDesktopBrowser(ua)
-	if !desktop{
-		deviceId := util.GetMobileCatchAllId(ua)
-		if deviceId != NO_MATCH{
-			return deviceId
-		}
-	}
-	if mobile{
-		return GENERIC_MOBILE
-	}
-	if desktop{
-		return GENERIC_WEB_BROWSER
-	}
-	return GENERIC
-}
-
-func (h *WindowsPhoneHandler) GetOrderedUAS() []string{
-	if len(h.OrderedUAS) == 0{
-		for k := range h.UASWithDeviceId{
-			h.OrderedUAS = append(h.OrderedUAS,k)
-		}
-		sort.Strings(h.OrderedUAS)
-	}
-	return h.OrderedUAS
-}
-
-func(h *WindowsPhoneHandler) GetDeviceIdFromRIS(ua string, tolerance int) string{
-	match := util.RISMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-func(h *WindowsPhoneHandler) GetDeviceIdFromLD(ua string, tolerance int) string{
-	match := util.LDMatch(h.GetOrderedUAS(),ua, tolerance)
-	if match != ""{
-		return h.UASWithDeviceId[match]
-	}
-	return NO_MATCH
-}
-
-
-func (h *WindowsPhoneHandler) IsBlankOrGeneric(deviceId string) bool{
-	return deviceId == "" || deviceId == GENERIC || len(strings.Trim(deviceId," ")) == 0
-}
-
-func (h *WindowsPhoneHandler) Match(ua string) string{
-	if h.CanHandle(ua){
-		return h.ApplyMatch(ua)
-	}
-	if h.nextHandler != nil{
-		return h.nextHandler.Match(ua)
-	}
-	return GENERIC
-}
-
-func (h *WindowsPhoneHandler) LookForMatchingUA(ua string) string{
-	tolerance := util.FirstSlash(ua)
-	return util.RISMatch(h.GetOrderedUAS(),ua,tolerance)
-}
-
-func (h *WindowsPhoneHandler) ApplyExactMatch(ua string) string{
-	for k := range h.UASWithDeviceId{
-		
-		if k == ua{
-			return h.UASWithDeviceId[k]
-		}
-	}
-	return NO_MATCH
-}
-
-func (h *WindowsPhoneHandler) ApplyMatch(ua string) string {
-	ua = h.Normalizer.Normalize(ua)
-	deviceId := h.ApplyExactMatch(ua)
-	if h.IsBlankOrGeneric(deviceId){
-		deviceId = h.ApplyConclusiveMatch(ua)
-		if h.IsBlankOrGeneric(deviceId){
-			deviceId = h.ApplyRecoveryMatch(ua)
-			if h.IsBlankOrGeneric(deviceId){
-				deviceId = h.ApplyRecoveryCatchAllMatch(ua)
-				if h.IsBlankOrGeneric(ua){
-					deviceId = GENERIC
-				}
-			}
-		}
-	}
-	return deviceId
-}
-
-func (h *WindowsPhoneHandler) Filter(ua string, deviceId string){
-	if h.CanHandle(ua){
-		h.UASWithDeviceId[h.Normalizer.Normalize(ua)] = deviceId
-		h.OrderedUAS = []string{}
-		return
-	}
-	if h.nextHandler != nil{
-		h.nextHandler.Filter(ua,deviceId)
-	}
-	return
-}
-
-func (h *WindowsPhoneHandler) SetNextHandler(hlr Handlers){
-	h.nextHandler = hlr
+func (wph *WindowsPhoneHandler) FixedIds() []string{
+	return wph.ConstantIds
 }
 
 func (wph *WindowsPhoneHandler) CanHandle(ua string) bool {
-	if util.IsDesktopBrowser(ua){
+	if wph.util.IsDesktopBrowser(ua){
 		return false
 	}
-	return util.CheckIfContains(ua,"Windows Phone")
+	return wph.util.CheckIfContains(ua,"Windows Phone")
 }
 
 func (wph *WindowsPhoneHandler) ApplyConclusiveMatch(ua string) string {
@@ -7858,13 +2296,13 @@ func (wph *WindowsPhoneHandler) ApplyConclusiveMatch(ua string) string {
 }
 
 func (wph *WindowsPhoneHandler) ApplyRecoveryMatch(ua string)string {
-	if util.CheckIfContains(ua,"Windows Phone 6.5"){
+	if wph.util.CheckIfContains(ua,"Windows Phone 6.5"){
 		return "generic_ms_winmo6_5"
 	}
-	if util.CheckIfContains(ua,"Windows Phone OS 7.0"){
+	if wph.util.CheckIfContains(ua,"Windows Phone OS 7.0"){
 		return "generic_ms_phone_os7"
 	}
-	if util.CheckIfContains(ua,"Windows Phone OS 7.5"){
+	if wph.util.CheckIfContains(ua,"Windows Phone OS 7.5"){
 		return "generic_ms_phone_os7_5"
 	}
 	return NO_MATCH
@@ -0,0 +1,199 @@
+package wurflgo
+
+import "testing"
+
+func TestDoCoMoRecovery(t *testing.T) {
+	h := NewDoCoMoHandler(CreateGenericNormalizers())
+	h.SetUtil(NewUtil())
+	for _, test := range []struct {
+		ua, want string
+	}{
+		{"DoCoMo", "docomo_generic_jap_ver1"},
+		{"DoCoMo/", "docomo_generic_jap_ver1"},
+		{"DoCoMo/1.0/N503i/c10", "docomo_generic_jap_ver1"},
+		{"DoCoMo/2.0 N905i(c100;TB;W24H16)", "docomo_generic_jap_ver2"},
+	} {
+		if got := h.ApplyRecoveryMatch(test.ua); got != test.want {
+			t.Errorf("ApplyRecoveryMatch(%q) = %s, want %s", test.ua, got, test.want)
+		}
+	}
+
+	e := newTestEngine(t, nil)
+	if res := e.Explain("DoCoMo"); res.Handler != "DoCoMoHandler" || res.DeviceId != "docomo_generic_jap_ver1" {
+		t.Errorf("Explain(DoCoMo) = %s by %s, want docomo_generic_jap_ver1 by DoCoMoHandler", res.DeviceId, res.Handler)
+	}
+}
+
+func TestAppleRecovery(t *testing.T) {
+	h := NewAppleHandler(CreateGenericNormalizers())
+	h.SetUtil(NewUtil())
+	for _, id := range []string{
+		"apple_iphone_ver1", "apple_iphone_ver9", "apple_iphone_ver10", "apple_iphone_ver11", "apple_iphone_ver17", "apple_iphone_ver17_1",
+		"apple_ipad_ver1", "apple_ipad_ver1_sub42", "apple_ipad_ver1_sub43", "apple_ipad_ver1_sub5", "apple_ipad_ver1_sub51",
+		"apple_ipad_ver1_sub10", "apple_ipad_ver1_sub12", "apple_ipad_ver1_sub12_1",
+		"apple_ipod_touch_ver5",
+	} {
+		h.Filter("Mozilla/5.0 (iPhone; "+id+")", id)
+	}
+	for _, test := range []struct {
+		ua, want string
+	}{
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", "apple_iphone_ver17_1"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1", "apple_iphone_ver17"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Mobile/15E148 Safari/604.1", "apple_iphone_ver17_1"},
+		// Versions newer than the table get the newest device.
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 18_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Mobile/15E148 Safari/604.1", "apple_iphone_ver17_1"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 10_3_3 like Mac OS X) AppleWebKit/603.3.8 (KHTML, like Gecko) Version/10.0 Mobile/14G60 Safari/602.1", "apple_iphone_ver10"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 9_3_5 like Mac OS X) AppleWebKit/601.1.46 (KHTML, like Gecko) Version/9.0 Mobile/13G36 Safari/601.1", "apple_iphone_ver9"},
+		{"Mozilla/5.0 (iPhone; U; CPU iPhone OS 4_0 like Mac OS X; en-us) AppleWebKit/532.9 (KHTML, like Gecko) Version/4.0.5 Mobile/8A293 Safari/6531.22.7", "apple_iphone_ver1"},
+		{"Mozilla/5.0 (iPhone; U; like Mac OS X)", "apple_iphone_ver1"},
+		// The first iPad ids write the minor version without a separator.
+		{"Mozilla/5.0 (iPad; U; CPU OS 3_2 like Mac OS X; en-us) AppleWebKit/531.21.10 (KHTML, like Gecko) Version/4.0.4 Mobile/7B334b Safari/531.21.10", "apple_ipad_ver1"},
+		{"Mozilla/5.0 (iPad; U; CPU OS 4_2_1 like Mac OS X; en-us) AppleWebKit/533.17.9 (KHTML, like Gecko) Version/5.0.2 Mobile/8C148 Safari/6533.18.5", "apple_ipad_ver1_sub42"},
+		{"Mozilla/5.0 (iPad; U; CPU OS 4_3_5 like Mac OS X; en-us) AppleWebKit/533.17.9 (KHTML, like Gecko) Version/5.0.2 Mobile/8L1 Safari/6533.18.5", "apple_ipad_ver1_sub43"},
+		{"Mozilla/5.0 (iPad; CPU OS 5_0 like Mac OS X) AppleWebKit/534.46 (KHTML, like Gecko) Version/5.1 Mobile/9A334 Safari/7534.48.3", "apple_ipad_ver1_sub5"},
+		{"Mozilla/5.0 (iPad; CPU OS 5_1_1 like Mac OS X) AppleWebKit/534.46 (KHTML, like Gecko) Version/5.1 Mobile/9B206 Safari/7534.48.3", "apple_ipad_ver1_sub51"},
+		{"Mozilla/5.0 (iPad; CPU OS 11_0 like Mac OS X) AppleWebKit/604.1.38 (KHTML, like Gecko) Version/11.0 Mobile/15A372 Safari/604.1", "apple_ipad_ver1_sub10"},
+		{"Mozilla/5.0 (iPad; CPU OS 12_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.0 Mobile/15E148 Safari/604.1", "apple_ipad_ver1_sub12"},
+		{"Mozilla/5.0 (iPad; CPU OS 12_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1.2 Mobile/15E148 Safari/604.1", "apple_ipad_ver1_sub12_1"},
+		// iPadOS in desktop mode tells the iOS version by the Safari one.
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1 Mobile/15E148 Safari/604.1", "apple_ipad_ver1_sub12_1"},
+		{"Mozilla/5.0 (iPod touch; CPU iPhone OS 6_1_6 like Mac OS X) AppleWebKit/536.26 (KHTML, like Gecko) Version/6.0 Mobile/10B500 Safari/8536.25", "apple_ipod_touch_ver5"},
+		{"Mozilla/5.0 (iPod; U; CPU iPhone OS 3_1_3 like Mac OS X; en-us) AppleWebKit/528.18 (KHTML, like Gecko) Version/4.0 Mobile/7E18 Safari/528.16", "apple_ipod_touch_ver1"},
+	} {
+		if got := h.ApplyRecoveryMatch(test.ua); got != test.want {
+			t.Errorf("ApplyRecoveryMatch(%q) = %s, want %s", test.ua, got, test.want)
+		}
+	}
+
+	// The versions do not depend on the other ids loaded.
+	h = NewAppleHandler(CreateGenericNormalizers())
+	h.SetUtil(NewUtil())
+	for _, id := range []string{"apple_ipad_ver1_sub42", "apple_ipad_ver1_sub12"} {
+		h.Filter("Mozilla/5.0 (iPad; "+id+")", id)
+	}
+	for _, test := range []struct {
+		ua, want string
+	}{
+		{"Mozilla/5.0 (iPad; U; CPU OS 4_2_1 like Mac OS X; en-us) AppleWebKit/533.17.9 (KHTML, like Gecko) Version/5.0.2 Mobile/8C148 Safari/6533.18.5", "apple_ipad_ver1_sub42"},
+		{"Mozilla/5.0 (iPad; CPU OS 11_0 like Mac OS X) AppleWebKit/604.1.38 (KHTML, like Gecko) Version/11.0 Mobile/15A372 Safari/604.1", "apple_ipad_ver1_sub42"},
+		{"Mozilla/5.0 (iPad; CPU OS 12_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.0 Mobile/15E148 Safari/604.1", "apple_ipad_ver1_sub12"},
+	} {
+		if got := h.ApplyRecoveryMatch(test.ua); got != test.want {
+			t.Errorf("ApplyRecoveryMatch(%q) = %s, want %s", test.ua, got, test.want)
+		}
+	}
+}
+
+func TestAppleUserAgents(t *testing.T) {
+	h := NewAppleHandler(CreateGenericNormalizers())
+	h.SetUtil(NewUtil())
+	for _, test := range []struct {
+		ua           string
+		major, minor int
+		webView      bool
+		desktopMode  bool
+	}{
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", 17, 1, false, false},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 10_3_3 like Mac OS X) AppleWebKit/603.3.8 (KHTML, like Gecko) Version/10.0 Mobile/14G60 Safari/602.1", 10, 3, false, false},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 9_3_5 like Mac OS X) AppleWebKit/601.1.46 (KHTML, like Gecko) Version/9.0 Mobile/13G36 Safari/601.1", 9, 3, false, false},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/119.0.6045.169 Mobile/15E148 Safari/604.1", 17, 1, false, false},
+		{"Mozilla/5.0 (iPhone; U; like Mac OS X)", -1, -1, false, false},
+		// Web views leave out Safari/ or send an app token.
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/21B80", 17, 1, true, false},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/21B80 [FBAN/FBIOS;FBAV/442.0.0.38.113]", 17, 1, true, false},
+		// iPadOS in desktop mode.
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1", 16, 6, false, true},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148", -1, -1, true, true},
+		// Safari on iPadOS in desktop mode sends the user agent of Safari
+		// on macOS, which is not told apart.
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15", 10, 15, false, false},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_6) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/12.1 Mobile/15E148 Safari/604.1", 10, 14, false, false},
+	} {
+		if major, minor := h.IOSVersion(test.ua); major != test.major || minor != test.minor {
+			t.Errorf("IOSVersion(%q) = %d, %d, want %d, %d", test.ua, major, minor, test.major, test.minor)
+		}
+		if got := h.IsWebView(test.ua); got != test.webView {
+			t.Errorf("IsWebView(%q) = %v, want %v", test.ua, got, test.webView)
+		}
+		if got := h.IsIPadDesktopMode(test.ua); got != test.desktopMode {
+			t.Errorf("IsIPadDesktopMode(%q) = %v, want %v", test.ua, got, test.desktopMode)
+		}
+	}
+}
+
+// TestMatchedIds pins the handler and device id the default chain gives a
+// fixed set of user agents with the test data, so that changes to the
+// handlers that move any of them show up here.
+func TestMatchedIds(t *testing.T) {
+	e := newTestEngine(t, nil)
+	for _, test := range []struct {
+		ua, handler, id string
+	}{
+		{"Alcatel-BG3/1.0", "AlcatelHandler", "generic"},
+		{"BenQ-M300", "BenQHandler", "generic"},
+		{"SIE-CX65/12", "SiemensHandler", "generic"},
+		{"Sanyo-Foo/1.0", "SanyoHandler", "generic"},
+		{"Vodafone/1.0/SFR", "VodafoneHandler", "generic_mobile"},
+		{"SHARP-TQ-GX10", "SharpHandler", "generic"},
+		{"Mozilla/5.0 Chrome", "ChromeHandler", "google_chrome"},
+		{"Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome", "ChromeHandler", "google_chrome"},
+		{"Chrome/120.0 Mobile", "CatchAllHandler", "generic_web_browser"},
+		{"BlackBerry8100/4.2.0 Profile/MIDP-2.0 Configuration/CLDC-1.1 VendorID/100", "BlackBerryHandler", "blackberry_generic_ver4_sub20"},
+		// The longest version code wins over 4., as UP.Browser/6.2 does over
+		// UP.Browser/6 below.
+		{"BlackBerry8700/4.1.0 Profile/MIDP-2.0 Configuration/CLDC-1.1 VendorID/100", "BlackBerryHandler", "blackberry_generic_ver4_sub10"},
+		{"BlackBerry9000/3.2.1 Profile/MIDP-2.0", "BlackBerryHandler", "blackberry_generic_ver3_sub2"},
+		{"Mozilla/5.0 (Linux; U; Android 4.0.4; en-gb; GT-I9300 Build/IMM76D) AppleWebKit/534.30 (KHTML, like Gecko) Version/4.0 Mobile Safari/534.30", "AndroidHandler", "samsung_gt_i9300_ver1"},
+		// Only the 4.0 build is in the test data.
+		{"Mozilla/5.0 (Linux; U; Android 4.1.2; en-us; GT-I9300 Build/JZO54K) AppleWebKit/534.30 (KHTML, like Gecko) Version/4.0 Mobile Safari/534.30", "AndroidHandler", "generic_mobile"},
+		{"Mozilla/5.0 (Linux; U; Android 2.3.4; en-us; Nexus S Build/GRJ22) AppleWebKit/533.1 (KHTML, like Gecko) Version/4.0 Mobile Safari/533.1", "AndroidHandler", "generic_mobile"},
+		{"Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36", "AndroidHandler", "generic_web_browser"},
+		{"Mozilla/5.0 (iPhone; U; CPU iPhone OS 4_0 like Mac OS X; en-us) AppleWebKit/532.9 (KHTML, like Gecko) Version/4.0.5 Mobile/8A293 Safari/6531.22.7", "AppleHandler", "apple_iphone_ver4"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", "AppleHandler", "apple_iphone_ver4"},
+		{"Mozilla/5.0 (iPad; U; CPU OS 3_2 like Mac OS X; en-us) AppleWebKit/531.21.10 (KHTML, like Gecko) Version/4.0.4 Mobile/7B334b Safari/531.21.10", "AppleHandler", "apple_ipad_ver1"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", "ChromeHandler", "google_chrome"},
+		// HTCMacHandler claims every Macintosh user agent.
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/119.0.0.0 Safari/537.36", "HTCMacHandler", "generic_android_htc_disguised_as_mac"},
+		{"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.5993.70 Safari/537.36", "ChromeHandler", "google_chrome"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0", "FirefoxHandler", "firefox"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15", "HTCMacHandler", "generic_android_htc_disguised_as_mac"},
+		{"Mozilla/5.0 (compatible; MSIE 9.0; Windows NT 6.1; Trident/5.0)", "MSIEHandler", "generic"},
+		{"Opera/9.80 (Windows NT 6.1; U; en) Presto/2.10.229 Version/11.62", "OperaHandler", "opera"},
+		{"Opera/9.80 (J2ME/MIDP; Opera Mini/9.80 (S60; SymbOS; Opera Mobi/23.348; U; en) Presto/2.5.25 Version/10.54", "OperaMiniHandler", "generic_opera_mini_version4"},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", "BotCrawlerTranscoderHandler", "googlebot"},
+		{"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)", "BotCrawlerTranscoderHandler", "googlebot"},
+		{"Nokia6300/2.0 (04.20) Profile/MIDP-2.0 Configuration/CLDC-1.1", "NokiaHandler", "generic_mobile"},
+		{"Mozilla/5.0 (SymbianOS/9.4; Series60/5.0 NokiaN97-1/12.0.024; Profile/MIDP-2.1 Configuration/CLDC-1.1; en-us) AppleWebKit/525 (KHTML, like Gecko) BrowserNG/7.1.18124", "NokiaHandler", "nokia_generic_series60"},
+		{"SonyEricssonK800i/R1KG Browser/NetFront/3.3 Profile/MIDP-2.0 Configuration/CLDC-1.1", "SonyEricssonHandler", "generic_netfront_ver3_3"},
+		// Crashed before handlers shared BaseHandler.
+		{"DoCoMo/2.0 N905i(c100;TB;W24H16)", "DoCoMoHandler", "docomo_generic_jap_ver2"},
+		{"KDDI-SA31 UP.Browser/6.2.0.7.3.129 (GUI) MMP/2.0", "KDDIHandler", "opwv_v62_generic"},
+		{"SAGEM-myX5-2/1.0 Profile/MIDP-2.0 Configuration/CLDC-1.0", "SagemHandler", "generic_mobile"},
+		{"BlackBerry9700/5.0.0.351 Profile/MIDP-2.1 Configuration/CLDC-1.1 VendorID/123", "BlackBerryHandler", "blackberry_generic_ver5"},
+		{"Mozilla/5.0 (BlackBerry; U; BlackBerry 9800; en) AppleWebKit/534.1+ (KHTML, like Gecko) Version/6.0.0.337 Mobile Safari/534.1+", "BlackBerryHandler", "generic_mobile"},
+		{"MOT-V3/0E.40.3CR MIB/2.2.1 Profile/MIDP-2.0 Configuration/CLDC-1.1", "MotorolaHandler", "mot_mib22_generic"},
+		{"SAMSUNG-SGH-E250/1.0 Profile/MIDP-2.0 Configuration/CLDC-1.1 UP.Browser/6.2.3.3.c.1.101 (GUI) MMP/2.0", "SamsungHandler", "opwv_v62_generic"},
+		{"LG-KU990/V10a Browser/Obigo-Q05A/3.6 MIDP-2.0/CLDC-1.1", "LGHandler", "generic_xhtml"},
+		{"Alcatel-OT-800/1.0 ObigoInternetBrowser/Q03C", "AlcatelHandler", "generic_xhtml"},
+		{"SIE-S65/25 UP.Browser/7.0.0.1.c.3 (GUI) MMP/2.0 Profile/MIDP-2.0 Configuration/CLDC-1.1", "SiemensHandler", "opwv_v7_generic"},
+		{"Sanyo-SCP5300/1.0 UP.Browser/6.2.3.4 (GUI) MMP/1.0", "SanyoHandler", "opwv_v62_generic"},
+		{"Mozilla/5.0 (Linux; U; en-US) AppleWebKit/528.5+ (KHTML, like Gecko, Safari/528.5+) Version/4.0 Kindle/3.0 (screen 600x800; rotate)", "KindleHandler", "amazon_kindle3_ver1"},
+		{"Mozilla/5.0 (webOS/1.4.0; U; en-US) AppleWebKit/532.2 (KHTML, like Gecko) Version/1.0 Safari/532.2 Pre/1.0", "WebOSHandler", "hp_webos_generic"},
+		{"Mozilla/5.0 (compatible; MSIE 10.0; Windows Phone 8.0; Trident/6.0; IEMobile/10.0; ARM; Touch; NOKIA; Lumia 920)", "WindowsPhoneHandler", "generic_mobile"},
+		{"Mozilla/5.0 (PlayStation 4 3.11) AppleWebKit/537.73 (KHTML, like Gecko)", "CatchAllHandler", "generic"},
+		{"Mozilla/5.0 (SMART-TV; Linux; Tizen 2.4.0) AppleWebKit/538.1 (KHTML, like Gecko) Version/2.4.0 TV Safari/538.1", "SafariHandler", "generic"},
+		{"HTC_Touch_HD_T8282 Mozilla/4.0 (compatible; MSIE 6.0; Windows CE; IEMobile 7.11)", "HTCMacHandler", "generic_android_htc_disguised_as_mac"},
+		{"curl/7.68.0", "CatchAllHandler", "generic"},
+		{"Chrome", "ChromeHandler", "google_chrome"},
+		{"Firefox", "FirefoxHandler", "firefox"},
+		{"", "CatchAllHandler", "generic"},
+	} {
+		if res := e.Explain(test.ua); res.Handler != test.handler || res.DeviceId != test.id {
+			t.Errorf("%q: got %s by %s, want %s by %s", test.ua, res.DeviceId, res.Handler, test.id, test.handler)
+		}
+		if id := e.Chain().Match(test.ua); id != test.id {
+			t.Errorf("%q: Chain.Match gives %s, want %s", test.ua, id, test.id)
+		}
+	}
+}
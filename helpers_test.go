@@ -0,0 +1,44 @@
+package wurflgo
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// testUAs are user agents of the devices in testdata/wurfl.xml and of
+// browsers and bots it only holds generic devices for.
+var testUAs = []string{
+	"Mozilla/5.0 (Linux; U; Android 4.0.4; en-gb; GT-I9300 Build/IMM76D) AppleWebKit/534.30 (KHTML, like Gecko) Version/4.0 Mobile Safari/534.30",
+	"Mozilla/5.0 (Linux; U; Android 4.1.2; en-us; GT-I9300 Build/JZO54K) AppleWebKit/534.30 (KHTML, like Gecko) Version/4.0 Mobile Safari/534.30",
+	"Mozilla/5.0 (iPhone; U; CPU iPhone OS 4_0 like Mac OS X; en-us) AppleWebKit/532.9 (KHTML, like Gecko) Version/4.0.5 Mobile/8A293 Safari/6531.22.7",
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+	"Mozilla/5.0 (iPad; U; CPU OS 3_2 like Mac OS X; en-us) AppleWebKit/531.21.10 (KHTML, like Gecko) Version/4.0.4 Mobile/7B334b Safari/531.21.10",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:121.0) Gecko/20100101 Firefox/121.0",
+	"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+	"Nokia6300/2.0 (04.20) Profile/MIDP-2.0 Configuration/CLDC-1.1",
+	"SonyEricssonK800i/R1KG Browser/NetFront/3.3 Profile/MIDP-2.0 Configuration/CLDC-1.1",
+	"DoCoMo/2.0 N905i(c100;TB;W24H16)",
+	"SAGEM-myX5-2/1.0 Profile/MIDP-2.0 Configuration/CLDC-1.0",
+	"",
+}
+
+func testXML(tb testing.TB) []byte {
+	tb.Helper()
+	data, err := os.ReadFile("testdata/wurfl.xml")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return data
+}
+
+// newTestEngine returns an engine holding testdata/wurfl.xml.
+func newTestEngine(tb testing.TB, opts *EngineOptions) *Engine {
+	tb.Helper()
+	e := NewEngine(opts)
+	if err := e.LoadXML(bytes.NewReader(testXML(tb)), nil); err != nil {
+		tb.Fatal(err)
+	}
+	return e
+}
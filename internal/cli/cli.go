@@ -0,0 +1,101 @@
+// Package cli holds what the commands share: the flags naming the data to
+// load and the loading itself.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/srinathgs/wurflgo"
+)
+
+// Data names the files an engine is loaded from.
+type Data struct {
+	Input    string
+	Snapshot string
+	// Patch and Groups are lists separated by commas.
+	Patch  string
+	Groups string
+	Rules  string
+}
+
+// DataFlags defines the -input, -snapshot, -patch, -groups and -rules
+// flags on the default flag set.
+func DataFlags() *Data {
+	d := &Data{}
+	flag.StringVar(&d.Input, "input", "", "Path to wurfl.xml")
+	flag.StringVar(&d.Snapshot, "snapshot", "", "Path to a snapshot written by parser -format snapshot, used instead of -input")
+	flag.StringVar(&d.Patch, "patch", "", "list of wurfl_patch.xml files separated by commas, applied in order")
+	flag.StringVar(&d.Groups, "groups", "", "list of capability groups to load separated by commas, all when empty")
+	flag.StringVar(&d.Rules, "rules", "", "Path to a JSON file of handler rules added to the handler chain")
+	return d
+}
+
+// Check reports flags that do not go together.
+func (d *Data) Check() error {
+	if (d.Input == "") == (d.Snapshot == "") {
+		return errors.New("Exactly one of -input and -snapshot is needed")
+	}
+	return nil
+}
+
+// Load reads the handler rules and then the devices into e. Given the
+// staging engine of Engine.Reload, it reloads the rules together with the
+// data.
+func (d *Data) Load(e *wurflgo.Engine) error {
+	rules, err := LoadRules(d.Rules)
+	if err != nil {
+		return err
+	}
+	if err := e.SetHandlerRules(rules); err != nil {
+		return err
+	}
+	if d.Snapshot != "" {
+		return WithFiles([]string{d.Snapshot}, func(files []io.Reader) error {
+			return e.LoadSnapshot(files[0])
+		})
+	}
+	paths := []string{d.Input}
+	if d.Patch != "" {
+		paths = append(paths, strings.Split(d.Patch, ",")...)
+	}
+	opts := &wurflgo.LoadOptions{}
+	if d.Groups != "" {
+		opts.Groups = strings.Split(d.Groups, ",")
+	}
+	return WithFiles(paths, func(files []io.Reader) error {
+		return e.LoadXML(files[0], opts, files[1:]...)
+	})
+}
+
+// LoadRules reads the handler rules file at path, if any.
+func LoadRules(path string) ([]wurflgo.HandlerRule, error) {
+	if path == "" {
+		return nil, nil
+	}
+	var rules []wurflgo.HandlerRule
+	err := WithFiles([]string{path}, func(files []io.Reader) error {
+		var err error
+		rules, err = wurflgo.LoadHandlerRules(files[0])
+		return err
+	})
+	return rules, err
+}
+
+// WithFiles opens every path, calls fn with the open files and closes
+// them again.
+func WithFiles(paths []string, fn func(files []io.Reader) error) error {
+	files := []io.Reader{}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		files = append(files, f)
+	}
+	return fn(files)
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/srinathgs/wurflgo"
+)
+
+// TestReloadRules checks that reloading with Data.Load, as wurfld does on
+// SIGHUP, picks up a changed rules file.
+func TestReloadRules(t *testing.T) {
+	rules := filepath.Join(t.TempDir(), "rules.json")
+	writeRules := func(contents string) {
+		if err := os.WriteFile(rules, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ua := "Mozilla/5.0 (Linux; U; Android 4.0.4; en-gb; GT-I9300 Build/IMM76D) AppleWebKit/534.30 (KHTML, like Gecko) Version/4.0 Mobile Safari/534.30"
+	d := &Data{Input: "../../testdata/wurfl.xml", Rules: rules}
+	e := wurflgo.NewEngine(nil)
+
+	for _, test := range []struct {
+		rules, handler string
+	}{
+		{`{"handlers": [{"name": "SamsungHandler", "before": "AndroidHandler", "contains": ["GT-I9300"]}]}`, "SamsungHandler"},
+		{`{"handlers": [{"name": "GalaxyHandler", "before": "AndroidHandler", "contains": ["GT-I9300"]}]}`, "GalaxyHandler"},
+		{`{"handlers": []}`, "AndroidHandler"},
+	} {
+		writeRules(test.rules)
+		if err := e.Reload(d.Load); err != nil {
+			t.Fatal(err)
+		}
+		if res := e.Explain(ua); res.Handler != test.handler || res.Device == nil || res.Device.Id != "samsung_gt_i9300_ver1" {
+			t.Errorf("%s: got %v by %s, want samsung_gt_i9300_ver1 by %s", test.rules, res.Device, res.Handler, test.handler)
+		}
+	}
+
+	// Invalid rules keep the engine as it was.
+	writeRules(`{"handlers": [{"name": "BadHandler"}]}`)
+	if err := e.Reload(d.Load); err == nil {
+		t.Error("invalid rules: no error")
+	}
+	if res := e.Explain(ua); res.Handler != "AndroidHandler" {
+		t.Errorf("invalid rules: got handler %s", res.Handler)
+	}
+}
@@ -0,0 +1,162 @@
+package wurflgo
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// LoadOptions controls how wurfl.xml is turned into registered devices.
+type LoadOptions struct {
+	// Groups lists the capability groups to keep, e.g. "product_info".
+	// Every group is kept when Groups is empty.
+	Groups []string
+}
+
+func (opts *LoadOptions) groupSet() *StringSet {
+	if opts == nil || len(opts.Groups) == 0 {
+		return nil
+	}
+	set := NewStringSet()
+	for _, g := range opts.Groups {
+		set.Add(strings.TrimSpace(g))
+	}
+	return set
+}
+
+type Capability struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type CapabilityGroup struct {
+	Id           string       `xml:"id,attr"`
+	Capabilities []Capability `xml:"capability"`
+}
+
+// DeviceDefinition is a <device> element of wurfl.xml, before it is
+// registered. FallBack is empty for the root device.
+type DeviceDefinition struct {
+	Id               string            `xml:"id,attr"`
+	FallBack         string            `xml:"fall_back,attr"`
+	UserAgent        string            `xml:"user_agent,attr"`
+	ActualDeviceRoot bool              `xml:"actual_device_root,attr"`
+	Groups           []CapabilityGroup `xml:"group"`
+}
+
+func (dev *DeviceDefinition) capabilities(groups *StringSet) map[string]interface{} {
+	caps := make(map[string]interface{})
+	for _, grp := range dev.Groups {
+		if groups != nil && !groups.Get(grp.Id) {
+			continue
+		}
+		for _, c := range grp.Capabilities {
+			caps[c.Name] = c.Value
+		}
+	}
+	return caps
+}
+
+// eachDeviceElement calls fn for every <device> start element of an XML
+// document, in document order.
+func eachDeviceElement(r io.Reader, fn func(dec *xml.Decoder, se *xml.StartElement) error) error {
+	dec := xml.NewDecoder(r)
+	for {
+		t, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		se, ok := t.(xml.StartElement)
+		if !ok || se.Name.Local != "device" {
+			continue
+		}
+		if err = fn(dec, &se); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadXML reads the device definitions of a wurfl.xml document, in
+// document order, and applies the given wurfl_patch.xml documents to them
+// one after the other. A *PatchError lists every conflict found in the
+// patches.
+func ReadXML(r io.Reader, patches ...io.Reader) ([]*DeviceDefinition, error) {
+	devices := []*DeviceDefinition{}
+	err := eachDeviceElement(r, func(dec *xml.Decoder, se *xml.StartElement) error {
+		dev := new(DeviceDefinition)
+		if err := dec.DecodeElement(dev, se); err != nil {
+			return err
+		}
+		if dev.FallBack == "root" {
+			dev.FallBack = ""
+		}
+		devices = append(devices, dev)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(patches) == 0 {
+		return devices, nil
+	}
+	return applyPatches(devices, patches)
+}
+
+// LoadXML reads a wurfl.xml document and registers every device it
+// contains. Devices whose fall_back has not been seen yet are held back
+// until their parent is registered, so the document may list devices in
+// any order. Patches, if any, are applied in order before registering.
+func LoadXML(r io.Reader, opts *LoadOptions, patches ...io.Reader) error {
+	return defaultEngine.LoadXML(r, opts, patches...)
+}
+
+func loadXML(e *Engine, rd io.Reader, opts *LoadOptions, patches []io.Reader) error {
+	if e.rulesErr != nil {
+		return e.rulesErr
+	}
+	devices, err := ReadXML(rd, patches...)
+	if err != nil {
+		return err
+	}
+	groups := opts.groupSet()
+	ds := e.current()
+	schema := InferCapabilitySchema(devices, ds.capabilityTypes(e.schema))
+	waiting := make(map[string][]*DeviceDefinition)
+	var register func(dev *DeviceDefinition) error
+	register = func(dev *DeviceDefinition) error {
+		if err := ds.register(dev.Id, dev.UserAgent, dev.ActualDeviceRoot, dev.capabilities(groups), dev.FallBack, schema); err != nil {
+			return err
+		}
+		children := waiting[dev.Id]
+		delete(waiting, dev.Id)
+		for _, child := range children {
+			if err := register(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, dev := range devices {
+		if dev.FallBack == "" || e.Find(dev.FallBack) != nil {
+			if err := register(dev); err != nil {
+				return err
+			}
+		} else {
+			waiting[dev.FallBack] = append(waiting[dev.FallBack], dev)
+		}
+	}
+	if len(waiting) > 0 {
+		missing := []string{}
+		for parent := range waiting {
+			missing = append(missing, parent)
+		}
+		sort.Strings(missing)
+		return errors.New("Unresolved fall_back devices: " + strings.Join(missing, ", "))
+	}
+	return nil
+}
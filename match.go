@@ -0,0 +1,226 @@
+package wurflgo
+
+import "reflect"
+
+// Stages of a handler's ApplyMatch, in the order they are tried.
+const (
+	StageExact            = "ApplyExactMatch"
+	StageConclusive       = "ApplyConclusiveMatch"
+	StageRecovery         = "ApplyRecoveryMatch"
+	StageRecoveryCatchAll = "ApplyRecoveryCatchAllMatch"
+)
+
+// Lookup methods reported in MatchResult.Method.
+const (
+	MethodExact = "exact"
+	MethodRIS   = "RIS"
+	MethodLD    = "LD"
+)
+
+// MatchResult explains how a user agent was matched.
+type MatchResult struct {
+	// Device is the matched device. When DeviceId is not loaded it is
+	// the device MatchE falls back to and Fallback is set.
+	Device   *Device
+	Fallback bool
+	// DeviceId is the id the handler chain returned.
+	DeviceId string
+	// UserAgent is the user agent as given; NormalizedUA is what the
+	// claiming handler made of it before looking it up.
+	UserAgent    string
+	NormalizedUA string
+	// Handler is the name of the handler that claimed the user agent,
+	// empty if none did. When a handler such as AppHandler hands another
+	// user agent to the handlers after it, Via is its name and the other
+	// fields describe the match of that user agent.
+	Handler string
+	Via     string
+	// Stage is the ApplyMatch stage that produced DeviceId.
+	Stage string
+	// Method, Tolerance and MatchedUA describe the table lookup that
+	// found DeviceId: the exact user agent, or the RIS or LD search with
+	// its tolerance. They are empty, with Tolerance -1, when the stage
+	// returned a fixed id such as a catch-all.
+	Method    string
+	Tolerance int
+	MatchedUA string
+}
+
+// Explain matches ua the way Match does and reports which handler, stage
+// and table lookup produced the device id. It runs copies of the handlers,
+// from the one claiming ua on, that record their matches in a matchTrace.
+// Handlers that do not embed BaseHandler are run as they are, and report
+// no stage when they claim ua.
+func (c *Chain) Explain(ua string) *MatchResult {
+	res := &MatchResult{UserAgent: ua, DeviceId: GENERIC, Tolerance: -1}
+	at := -1
+	for i, h := range c.Handlers {
+		if h.CanHandle(ua) {
+			at = i
+			break
+		}
+	}
+	if at < 0 {
+		return res
+	}
+	trace := &matchTrace{}
+	res.Handler = HandlerName(c.Handlers[at])
+	res.DeviceId = c.traced(c.Handlers[at:], trace).ApplyMatch(ua)
+	step := trace.root
+	if step == nil {
+		res.NormalizedUA = c.Handlers[at].GetNormalizer().Normalize(ua)
+		return res
+	}
+	// Follow the user agents handed to the handlers after it, see
+	// AppHandler.
+	for step.sub != nil && step.sub.deviceId == step.deviceId {
+		step = step.sub
+		res.Via = HandlerName(c.Handlers[at])
+	}
+	res.Handler = HandlerName(step.handler)
+	res.NormalizedUA, res.Stage = step.ua, step.stage
+	if step.stage == StageExact {
+		res.Method, res.MatchedUA = MethodExact, step.ua
+		return res
+	}
+	tables := handlerTables(step.handler)
+	for _, l := range step.lookups {
+		for _, t := range tables {
+			if id, found := t.ids[l.match]; found && id == step.deviceId {
+				res.Method, res.Tolerance, res.MatchedUA = l.method, l.tolerance, l.match
+			}
+		}
+	}
+	return res
+}
+
+// traced returns a copy of the first of handlers, linked to copies of the
+// others, whose matches are recorded in trace. The copies share the
+// tables of the handlers. A handler that cannot be copied ends the copies
+// and is linked as it is.
+func (c *Chain) traced(handlers []Handlers, trace *matchTrace) Handlers {
+	if c.util == nil {
+		return handlers[0]
+	}
+	u := c.util.traced(trace)
+	var first, last Handlers
+	for _, h := range handlers {
+		next := copyHandler(h, u)
+		if next == nil {
+			next = h
+		}
+		if last == nil {
+			first = next
+		} else {
+			last.SetNextHandler(next)
+		}
+		if next == h {
+			return first
+		}
+		last = next
+	}
+	last.SetNextHandler(nil)
+	return first
+}
+
+// copyHandler returns a shallow copy of h matching with u, or nil if h
+// does not embed a BaseHandler.
+func copyHandler(h Handlers, u *Util) Handlers {
+	b, ok := h.(interface{ base() *BaseHandler })
+	v := reflect.ValueOf(h)
+	if !ok || v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	c := reflect.New(v.Elem().Type())
+	c.Elem().Set(v.Elem())
+	cp := c.Interface().(Handlers)
+	base := cp.(interface{ base() *BaseHandler }).base()
+	if base == b.base() {
+		// The BaseHandler is embedded by pointer and shared.
+		return nil
+	}
+	base.self, base.util = cp, u
+	return cp
+}
+
+// Explain matches ua and reports how it was matched. See Chain.Explain.
+func (e *Engine) Explain(ua string) *MatchResult {
+	ds := e.current()
+	ds.rlock()
+	defer ds.mu.RUnlock()
+	res := ds.chain.Explain(ua)
+	var err error
+	res.Device, err = ds.resolve(e.util, ua, res.DeviceId)
+	res.Fallback = err != nil
+	return res
+}
+
+func Explain(ua string) *MatchResult {
+	return defaultEngine.Explain(ua)
+}
+
+type lookup struct {
+	method    string
+	tolerance int
+	match     string
+}
+
+// matchTrace records the stages and lookups of the matches run by the
+// handlers Explain copies. Only the goroutine running Explain uses it.
+type matchTrace struct {
+	root    *matchStep
+	current *matchStep
+}
+
+// matchStep is the ApplyMatch of one handler.
+type matchStep struct {
+	handler  Handlers
+	ua       string
+	stage    string
+	deviceId string
+	// lookups are the RIS and LD lookups of the stage, and sub the last
+	// ApplyMatch of another handler it ran, such as the one an AppHandler
+	// hands its browser user agent to.
+	lookups []lookup
+	sub     *matchStep
+	parent  *matchStep
+}
+
+// begin records the start of the ApplyMatch of h for the normalized ua.
+// It returns nil, which the other methods ignore, on a nil trace.
+func (t *matchTrace) begin(h Handlers, ua string) *matchStep {
+	if t == nil {
+		return nil
+	}
+	step := &matchStep{handler: h, ua: ua, parent: t.current}
+	if t.current == nil {
+		t.root = step
+	}
+	t.current = step
+	return step
+}
+
+func (t *matchTrace) end(step *matchStep, deviceId string) {
+	if t == nil {
+		return
+	}
+	step.deviceId = deviceId
+	t.current = step.parent
+	if step.parent != nil {
+		step.parent.sub = step
+	}
+}
+
+func (t *matchTrace) lookup(method string, tolerance int, match string) {
+	if t == nil || t.current == nil {
+		return
+	}
+	t.current.lookups = append(t.current.lookups, lookup{method, tolerance, match})
+}
+
+func (step *matchStep) enter(stage string) {
+	if step == nil {
+		return
+	}
+	step.stage, step.lookups, step.sub = stage, nil, nil
+}
@@ -0,0 +1,62 @@
+package wurflgo
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	e := newTestEngine(t, nil)
+	if err := e.RegisterDevice("test_bar", "Mozilla/5.0 (X11; FooOS) Bar/1.0", false, nil, "generic_web_browser"); err != nil {
+		t.Fatal(err)
+	}
+	googlebot := "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	for _, test := range []struct {
+		ua, id, handler, stage, method string
+		tolerance                      int
+		matchedUA                      string
+	}{
+		{googlebot, "googlebot", "BotCrawlerTranscoderHandler", StageExact, MethodExact, -1, googlebot},
+		{"Mozilla/5.0 (compatible; Googlebot/2.2; +http://www.google.com/bot.html)", "googlebot", "BotCrawlerTranscoderHandler", StageConclusive, MethodRIS, 7, googlebot},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", "google_chrome", "ChromeHandler", StageConclusive, MethodRIS, 6, "Chrome"},
+		{"Mozilla/5.0 (X11; FooOS) Bar/1.1", "test_bar", "CatchAllHandler", StageConclusive, MethodLD, 5, "Mozilla/5.0 (X11; FooOS) Bar/1.0"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", "apple_iphone_ver4", "AppleHandler", StageRecovery, "", -1, ""},
+		{"Foo/1.0 Profile/MIDP-2.0", "generic_mobile", "CatchAllHandler", StageRecoveryCatchAll, "", -1, ""},
+	} {
+		res := e.Explain(test.ua)
+		if res.DeviceId != test.id || res.Handler != test.handler || res.Stage != test.stage {
+			t.Errorf("%q: got %s by %s/%s, want %s by %s/%s", test.ua, res.DeviceId, res.Handler, res.Stage, test.id, test.handler, test.stage)
+		}
+		if res.Method != test.method || res.Tolerance != test.tolerance || res.MatchedUA != test.matchedUA {
+			t.Errorf("%q: got %q with tolerance %d on %q, want %q with tolerance %d on %q", test.ua, res.Method, res.Tolerance, res.MatchedUA, test.method, test.tolerance, test.matchedUA)
+		}
+		if id := e.Match(test.ua).Id; id != res.DeviceId {
+			t.Errorf("%q: Match gives %s, Explain %s", test.ua, id, res.DeviceId)
+		}
+	}
+}
+
+// TestExplainWhileMatching checks that matches of the same user agent
+// running beside Explain do not change what it reports. Run it with
+// -race.
+func TestExplainWhileMatching(t *testing.T) {
+	e := newTestEngine(t, nil)
+	ua := "Mozilla/5.0 (compatible; Googlebot/2.2; +http://www.google.com/bot.html)"
+	want := *e.Explain(ua)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				e.Match(ua)
+			}
+		}()
+	}
+	for n := 0; n < 100; n++ {
+		if got := *e.Explain(ua); got != want {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,155 @@
+package matcher
+
+import "sort"
+
+// AhoCorasick finds which of a fixed set of patterns occur in a string
+// with a single pass over the string, however many patterns there are.
+// It is safe for concurrent use once built.
+type AhoCorasick struct {
+	nodes []acNode
+	// root holds the edges of the root, where a scan spends most of its
+	// time, by label.
+	root [256]int32
+}
+
+type acNode struct {
+	// edges is sorted by label.
+	edges []acEdge
+	fail  int32
+	// pattern is the index of the shortest pattern ending at this node or
+	// one reachable through its fail links, or -1.
+	pattern int32
+}
+
+type acEdge struct {
+	label byte
+	next  int32
+}
+
+// NewAhoCorasick builds the automaton for patterns. Empty patterns are
+// ignored.
+func NewAhoCorasick(patterns []string) *AhoCorasick {
+	ac := &AhoCorasick{nodes: []acNode{{pattern: -1}}}
+	for i, p := range patterns {
+		if p == "" {
+			continue
+		}
+		n := int32(0)
+		for j := 0; j < len(p); j++ {
+			next := ac.child(n, p[j])
+			if next < 0 {
+				next = int32(len(ac.nodes))
+				ac.nodes = append(ac.nodes, acNode{pattern: -1})
+				ac.addEdge(n, p[j], next)
+			}
+			n = next
+		}
+		if ac.nodes[n].pattern < 0 || len(patterns[ac.nodes[n].pattern]) > len(p) {
+			ac.nodes[n].pattern = int32(i)
+		}
+	}
+
+	// Breadth first, so that the fail link of a node is done before its
+	// children need it.
+	queue := []int32{}
+	for _, e := range ac.nodes[0].edges {
+		queue = append(queue, e.next)
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, e := range ac.nodes[n].edges {
+			f := ac.nodes[n].fail
+			for {
+				if next := ac.child(f, e.label); next >= 0 {
+					ac.nodes[e.next].fail = next
+					break
+				}
+				if f == 0 {
+					break
+				}
+				f = ac.nodes[f].fail
+			}
+			if inherited := ac.nodes[ac.nodes[e.next].fail].pattern; inherited >= 0 {
+				own := ac.nodes[e.next].pattern
+				if own < 0 || len(patterns[inherited]) < len(patterns[own]) {
+					ac.nodes[e.next].pattern = inherited
+				}
+			}
+			queue = append(queue, e.next)
+		}
+	}
+	for i := range ac.root {
+		ac.root[i] = ac.child(0, byte(i))
+	}
+	return ac
+}
+
+func (ac *AhoCorasick) child(n int32, label byte) int32 {
+	edges := ac.nodes[n].edges
+	// Most nodes have a single edge.
+	if len(edges) <= 8 {
+		for _, e := range edges {
+			if e.label == label {
+				return e.next
+			}
+		}
+		return -1
+	}
+	lo, hi := 0, len(edges)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if edges[mid].label < label {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	if lo < len(edges) && edges[lo].label == label {
+		return edges[lo].next
+	}
+	return -1
+}
+
+func (ac *AhoCorasick) addEdge(n int32, label byte, next int32) {
+	edges := ac.nodes[n].edges
+	i := sort.Search(len(edges), func(i int) bool { return edges[i].label >= label })
+	edges = append(edges, acEdge{})
+	copy(edges[i+1:], edges[i:])
+	edges[i] = acEdge{label, next}
+	ac.nodes[n].edges = edges
+}
+
+// Find returns the index of the pattern whose first occurrence in s ends
+// first, the shortest one when several end at the same byte, or -1 if no
+// pattern occurs in s.
+func (ac *AhoCorasick) Find(s string) int {
+	if ac == nil {
+		return -1
+	}
+	n := int32(0)
+	for i := 0; i < len(s); i++ {
+		for {
+			if n == 0 {
+				if next := ac.root[s[i]]; next >= 0 {
+					n = next
+				}
+				break
+			}
+			if next := ac.child(n, s[i]); next >= 0 {
+				n = next
+				break
+			}
+			n = ac.nodes[n].fail
+		}
+		if p := ac.nodes[n].pattern; p >= 0 {
+			return int(p)
+		}
+	}
+	return -1
+}
+
+// ContainsAny reports whether any of the patterns occurs in s.
+func (ac *AhoCorasick) ContainsAny(s string) bool {
+	return ac.Find(s) >= 0
+}
@@ -0,0 +1,96 @@
+package matcher
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAhoCorasick(t *testing.T) {
+	for _, test := range []struct {
+		patterns []string
+		s        string
+		// want is the pattern Find returns, "" for none.
+		want string
+	}{
+		{nil, "anything", ""},
+		{[]string{}, "", ""},
+		{[]string{""}, "anything", ""},
+		{[]string{"", "b"}, "abc", "b"},
+		{[]string{"mobile"}, "", ""},
+		{[]string{"mobile"}, "Mobile", ""},
+		{[]string{"mobile"}, "mobile", "mobile"},
+		{[]string{"mobile"}, "x mobile safari", "mobile"},
+		{[]string{"mobile"}, "mobil", ""},
+		// Overlapping patterns: the first occurrence to end wins, the
+		// shortest when several end together.
+		{[]string{"he", "she", "hers"}, "ushers", "he"},
+		{[]string{"she", "he"}, "ushers", "he"},
+		{[]string{"hers", "she"}, "ushers", "she"},
+		{[]string{"abcd", "bc"}, "abcd", "bc"},
+		{[]string{"abcd", "bcx"}, "abcbcx", "bcx"},
+		{[]string{"aab"}, "aaab", "aab"},
+		{[]string{"phone", "iphone", "smartphone"}, "apple iphone", "phone"},
+		{[]string{"opera mini", "opera mobi"}, "opera mobi/10", "opera mobi"},
+		{[]string{"a", "a"}, "a", "a"},
+	} {
+		ac := NewAhoCorasick(test.patterns)
+		got := ""
+		if i := ac.Find(test.s); i >= 0 {
+			got = test.patterns[i]
+		}
+		if got != test.want {
+			t.Errorf("%q in %q: Find gives %q, want %q", test.patterns, test.s, got, test.want)
+		}
+		if ac.ContainsAny(test.s) != (test.want != "") {
+			t.Errorf("%q in %q: ContainsAny = %v", test.patterns, test.s, !(test.want != ""))
+		}
+	}
+
+	var ac *AhoCorasick
+	if ac.ContainsAny("anything") {
+		t.Errorf("nil AhoCorasick contains a pattern")
+	}
+}
+
+// TestAhoCorasickLoop checks ContainsAny against a scan of the patterns
+// on every substring of a few strings.
+func TestAhoCorasickLoop(t *testing.T) {
+	patterns := []string{"midp", "mobile", "android", "mob", "ile", "droid", "e m", "x"}
+	ac := NewAhoCorasick(patterns)
+	for _, s := range []string{"linux android mobile safari", "j2me/midp-2.0", "windows nt 10.0"} {
+		for i := 0; i <= len(s); i++ {
+			for j := i; j <= len(s); j++ {
+				sub := s[i:j]
+				want := false
+				for _, p := range patterns {
+					want = want || strings.Contains(sub, p)
+				}
+				if got := ac.ContainsAny(sub); got != want {
+					t.Errorf("ContainsAny(%q) = %v, want %v", sub, got, want)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkAhoCorasick(b *testing.B) {
+	patterns := []string{"midp", "mobile", "android", "samsung", "nokia", "up.browser", "phone", "opera mini", "opera mobi", "brew", "sonyericsson", "blackberry", "netfront", "uc browser", "symbian", "j2me", "wap2.", "up.link", " arm;", "windows ce", "vodafone", "ucweb", "zte-", "ipod;", "ipad;"}
+	s := "mozilla/5.0 (windows nt 10.0; win64; x64) applewebkit/537.36 (khtml, like gecko) chrome/120.0.0.0 safari/537.36"
+	ac := NewAhoCorasick(patterns)
+	b.Run("ahocorasick", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			ac.ContainsAny(s)
+		}
+	})
+	b.Run("loop", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, p := range patterns {
+				if strings.Contains(s, p) {
+					break
+				}
+			}
+		}
+	})
+}
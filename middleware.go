@@ -0,0 +1,112 @@
+package wurflgo
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// MiddlewareOptions configures Middleware.
+type MiddlewareOptions struct {
+	// Engine matches the requests. The default engine is used when it is
+	// nil.
+	Engine *Engine
+
+	// Vary adds the headers that detection depends on to the Vary
+	// response header, for caches in front of the service.
+	Vary bool
+	// AcceptCH asks browsers for the client hints the engine uses, see
+	// AcceptCHHeader.
+	AcceptCH bool
+
+	// Requests whose path starts with one of SkipPaths, whose method is
+	// one of SkipMethods or for which Skip returns true are passed on
+	// without detection.
+	SkipPaths   []string
+	SkipMethods []string
+	Skip        func(r *http.Request) bool
+}
+
+// Detection is what Middleware stores in the request context.
+type Detection struct {
+	Device *Device
+	// UserAgent is the user agent the device was matched from, see
+	// Engine.RequestUA.
+	UserAgent string
+
+	engine *Engine
+}
+
+// VirtualCapability computes a virtual capability for the detected
+// device, see Engine.VirtualCapability.
+func (d *Detection) VirtualCapability(name string) (interface{}, error) {
+	return d.engine.VirtualCapability(d.Device, d.UserAgent, name)
+}
+
+type detectionKey struct{}
+
+// NewContext returns a copy of ctx carrying d.
+func NewContext(ctx context.Context, d *Detection) context.Context {
+	return context.WithValue(ctx, detectionKey{}, d)
+}
+
+// FromContext returns the detection Middleware stored in ctx.
+func FromContext(ctx context.Context) (*Detection, bool) {
+	d, ok := ctx.Value(detectionKey{}).(*Detection)
+	return d, ok
+}
+
+// Middleware matches every request once with MatchRequest and stores the
+// result in its context for next, which reads it with FromContext.
+//
+//	http.ListenAndServe(":8080", wurflgo.Middleware(mux, nil))
+func Middleware(next http.Handler, opts *MiddlewareOptions) http.Handler {
+	if opts == nil {
+		opts = &MiddlewareOptions{}
+	}
+	e := opts.Engine
+	if e == nil {
+		e = defaultEngine
+	}
+	skipMethods := NewStringSet()
+	for _, m := range opts.SkipMethods {
+		skipMethods.Add(strings.ToUpper(m))
+	}
+	vary := ""
+	if opts.Vary {
+		headers := append([]string(nil), e.uaHeaders...)
+		if !e.ignoreClientHints {
+			headers = append(headers, HeaderSecCHUA, HeaderSecCHUAMobile, HeaderSecCHUAPlatform)
+			headers = append(headers, AcceptCH...)
+		}
+		headers = append(headers, UAProfHeader)
+		vary = strings.Join(headers, ", ")
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if opts.skip(r, skipMethods) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if vary != "" {
+			w.Header().Add("Vary", vary)
+		}
+		if opts.AcceptCH {
+			w.Header().Set("Accept-CH", AcceptCHHeader())
+		}
+		d := &Detection{UserAgent: e.RequestUA(r), engine: e}
+		d.Device = e.matchRequest(r, d.UserAgent)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), d)))
+	})
+}
+
+func (opts *MiddlewareOptions) skip(r *http.Request, skipMethods *StringSet) bool {
+	if skipMethods.Get(r.Method) {
+		return true
+	}
+	for _, p := range opts.SkipPaths {
+		if strings.HasPrefix(r.URL.Path, p) {
+			return true
+		}
+	}
+	return opts.Skip != nil && opts.Skip(r)
+}
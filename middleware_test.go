@@ -0,0 +1,123 @@
+package wurflgo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveMiddleware runs req through Middleware and returns the response
+// and the detection the next handler found, nil when there was none.
+func serveMiddleware(opts *MiddlewareOptions, req *http.Request) (*httptest.ResponseRecorder, *Detection) {
+	var detection *Detection
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		detection, _ = FromContext(r.Context())
+	})
+	w := httptest.NewRecorder()
+	Middleware(next, opts).ServeHTTP(w, req)
+	return w, detection
+}
+
+func TestMiddleware(t *testing.T) {
+	e := newTestEngine(t, nil)
+	req := testRequest(map[string]string{"User-Agent": testOperaUA, "X-OperaMini-Phone-UA": testSamsungUA})
+	w, d := serveMiddleware(&MiddlewareOptions{Engine: e}, req)
+	if d == nil {
+		t.Fatal("no detection in the request context")
+	}
+	if d.UserAgent != testSamsungUA || d.Device == nil || d.Device.Id != "samsung_gt_i9300_ver1" {
+		t.Errorf("got %v for %q, want samsung_gt_i9300_ver1", d.Device, d.UserAgent)
+	}
+	if app, err := d.VirtualCapability("is_app"); err != nil || app != false {
+		t.Errorf("is_app = %v, %v", app, err)
+	}
+	if vary, acceptCH := w.Header().Get("Vary"), w.Header().Get("Accept-CH"); vary != "" || acceptCH != "" {
+		t.Errorf("got Vary %q and Accept-CH %q without asking for them", vary, acceptCH)
+	}
+}
+
+func TestMiddlewareSkip(t *testing.T) {
+	e := newTestEngine(t, nil)
+	opts := &MiddlewareOptions{
+		Engine:      e,
+		Vary:        true,
+		AcceptCH:    true,
+		SkipPaths:   []string{"/static/", "/health"},
+		SkipMethods: []string{"options", "HEAD"},
+		Skip:        func(r *http.Request) bool { return r.Header.Get("X-Internal") != "" },
+	}
+	for _, test := range []struct {
+		method, path string
+		internal     bool
+		skipped      bool
+	}{
+		{"GET", "/", false, false},
+		{"POST", "/static", false, false},
+		{"GET", "/static/app.js", false, true},
+		{"GET", "/healthz", false, true},
+		{"OPTIONS", "/", false, true},
+		{"HEAD", "/", false, true},
+		{"GET", "/", true, true},
+	} {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		req.Header.Set("User-Agent", testSamsungUA)
+		if test.internal {
+			req.Header.Set("X-Internal", "1")
+		}
+		w, d := serveMiddleware(opts, req)
+		if skipped := d == nil; skipped != test.skipped {
+			t.Errorf("%s %s internal %v: skipped %v, want %v", test.method, test.path, test.internal, skipped, test.skipped)
+		}
+		// Skipped requests get the response of next alone.
+		if hasHeaders := w.Header().Get("Vary") != ""; hasHeaders == test.skipped {
+			t.Errorf("%s %s internal %v: got Vary %q", test.method, test.path, test.internal, w.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestMiddlewareHeaders(t *testing.T) {
+	for _, ignoreClientHints := range []bool{false, true} {
+		e := newTestEngine(t, &EngineOptions{IgnoreClientHints: ignoreClientHints})
+		w, _ := serveMiddleware(&MiddlewareOptions{Engine: e, Vary: true, AcceptCH: true}, testRequest(map[string]string{"User-Agent": testSamsungUA}))
+		vary := strings.Split(w.Header().Get("Vary"), ", ")
+		want := append([]string(nil), DefaultUAHeaders...)
+		if !ignoreClientHints {
+			want = append(want, HeaderSecCHUA, HeaderSecCHUAMobile, HeaderSecCHUAPlatform)
+			want = append(want, AcceptCH...)
+		}
+		want = append(want, UAProfHeader)
+		if strings.Join(vary, ", ") != strings.Join(want, ", ") {
+			t.Errorf("IgnoreClientHints %v: got Vary %q, want %q", ignoreClientHints, vary, want)
+		}
+		if got := w.Header().Get("Accept-CH"); got != "Sec-CH-UA-Model, Sec-CH-UA-Platform-Version, Sec-CH-UA-Full-Version-List" {
+			t.Errorf("IgnoreClientHints %v: got Accept-CH %q", ignoreClientHints, got)
+		}
+	}
+
+	// Vary keeps the values set by the handlers before.
+	e := newTestEngine(t, nil)
+	outer := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			next.ServeHTTP(w, r)
+		})
+	}
+	w := httptest.NewRecorder()
+	outer(Middleware(http.NotFoundHandler(), &MiddlewareOptions{Engine: e, Vary: true})).ServeHTTP(w, testRequest(nil))
+	if vary := w.Header().Values("Vary"); len(vary) != 2 || vary[0] != "Accept-Encoding" {
+		t.Errorf("got Vary %q", vary)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	if d, ok := FromContext(req.Context()); ok || d != nil {
+		t.Errorf("got %v, %v from a request without a detection", d, ok)
+	}
+	d := &Detection{UserAgent: "ua"}
+	if got, ok := FromContext(NewContext(context.Background(), d)); !ok || got != d {
+		t.Errorf("got %v, %v, want the stored detection", got, ok)
+	}
+}
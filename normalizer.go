@@ -5,6 +5,13 @@ type Normalizer interface{
 	Normalize(string) string
 }
 
+// utilNormalizer is implemented by the normalizers that use the Util of
+// the chain. The handler they belong to passes it on, see
+// BaseHandler.SetUtil.
+type utilNormalizer interface{
+	SetUtil(*Util)
+}
+
 type Null struct{
 
 }
@@ -27,6 +34,14 @@ func (UANorm *UserAgentNormalizer) AddNormalizer(norm Normalizer) *UserAgentNorm
 	return NewUserAgentNormalizer(append(UANorm.normalizers,norm))
 }
 
+func (UANorm *UserAgentNormalizer) SetUtil(u *Util){
+	for i := range UANorm.normalizers{
+		if n, ok := UANorm.normalizers[i].(utilNormalizer); ok{
+			n.SetUtil(u)
+		}
+	}
+}
+
 func (UANorm *UserAgentNormalizer) Normalize(ua string) string{
 	normalizedUA := ua 
 	for i := range UANorm.normalizers{
@@ -1,13 +1,19 @@
 //./parser -groups product_info,xhtml_ui -input <path to>/wurfl.xml -output <path to>/wurfl.go
+//./parser -format snapshot -groups product_info,xhtml_ui -input <path to>/wurfl.xml -output <path to>/wurfl.snapshot
+//./parser -validate -input <path to>/wurfl.xml
+//./parser -groups product_info -input <path to>/wurfl.xml -patch <path to>/patch1.xml,<path to>/patch2.xml -output <path to>/wurfl.go
 
 package main 
 
 import (
 	"flag"
 	"fmt"
-	"encoding/xml"
+	"io"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"github.com/srinathgs/wurflgo"
 	)
 
 type WurflProcessor struct{
@@ -16,11 +22,15 @@ type WurflProcessor struct{
 	ProcessedDevices *StringSet
 	OutFile *os.File
 	InFile *os.File
+	PatchFiles []*os.File
 	DeviceList map[string]*Device
+	// Schema gives every capability the type of its values on all the
+	// devices.
+	Schema wurflgo.CapabilitySchema
 }
 
 
-func NewWurflProcessor(groups string,infile string, outfile string) (*WurflProcessor, error){
+func NewWurflProcessor(groups string,infile string, outfile string, patches []string) (*WurflProcessor, error){
 	gpSet := NewStringSet()
 	gps := strings.Split(groups,",")
 	for _,V := range gps {
@@ -36,6 +46,13 @@ func NewWurflProcessor(groups string,infile string, outfile string) (*WurflProce
 	if err != nil{
 		return nil,err
 	}
+	for _,patch := range patches{
+		f,err := os.Open(patch)
+		if err != nil{
+			return nil,err
+		}
+		wurflp.PatchFiles = append(wurflp.PatchFiles,f)
+	}
 	wurflp.OutFile,err = os.Create(outfile)
 	if err != nil{
 		return nil,err
@@ -43,44 +60,65 @@ func NewWurflProcessor(groups string,infile string, outfile string) (*WurflProce
 	return wurflp,nil
 }
 
-func (wp *WurflProcessor) Process(){
+func (wp *WurflProcessor) Process() error{
 	defer wp.InFile.Close()
 	defer wp.OutFile.Close()
+	for _,f := range wp.PatchFiles{
+		defer f.Close()
+	}
 	wp.DumpHeader()
-	var err error
-	dec := xml.NewDecoder(wp.InFile)
-	for {
-		t,_ := dec.Token()
-		if t == nil {
-			break
-		}
-		switch se := t.(type){
-		case xml.StartElement:
-			if se.Name.Local == "device"{
-				dev := new(Device)
-				if err = dec.DecodeElement(dev,&se); err == nil{
-					wp.DeviceList[dev.Id] = dev
-				}
-				if dev.Parent == "" || dev.Parent == "root"{
-					dev.Parent = ""
-					wp.DumpDevice(dev)
-				} else {
-					if wp.ProcessedDevices.Get(dev.Parent){
-						wp.DumpDevice(dev)
-					} else {
-						wp.DeferredDevices = append(wp.DeferredDevices,dev.Id)
-					}
-				}
-				
-				//fmt.Printf("%#v\n",se)
-		}
+	if err := wp.processDevices(); err != nil{
+		return err
+	}
+	if err := wp.ProcessDeferredDevices(); err != nil{
+		return err
+	}
+
+	wp.DumpFooter()
+	return nil
+}
 
-		//fmt.Printf("%#v\n",t)
+// processDevices reads the whole input with its patches applied before
+// dumping anything, since a patch may change any device of the input and
+// the type of a capability depends on its values on every device.
+func (wp *WurflProcessor) processDevices() error{
+	patches := make([]io.Reader,len(wp.PatchFiles))
+	for i,f := range wp.PatchFiles{
+		patches[i] = f
+	}
+	defs,err := wurflgo.ReadXML(wp.InFile,patches...)
+	if err != nil{
+		return err
+	}
+	wp.Schema = wurflgo.InferCapabilitySchema(defs,wurflgo.DefaultCapabilitySchema)
+	for _,def := range defs{
+		dev := &Device{Id: def.Id, Parent: def.FallBack, UserAgent: def.UserAgent, ActualDeviceRoot: def.ActualDeviceRoot}
+		for _,grp := range def.Groups{
+			g := Grp{Id: grp.Id}
+			for _,c := range grp.Capabilities{
+				g.Capabilities = append(g.Capabilities,Capability{Name: c.Name, Value: c.Value})
+			}
+			dev.Group = append(dev.Group,g)
 		}
+		wp.AddDevice(dev)
 	}
-	wp.ProcessDeferredDevices()
+	return nil
+}
 
-	wp.DumpFooter()
+// AddDevice dumps dev right away if its parent has been dumped already
+// and defers it otherwise.
+func (wp *WurflProcessor) AddDevice(dev *Device){
+	wp.DeviceList[dev.Id] = dev
+	if dev.Parent == "" || dev.Parent == "root"{
+		dev.Parent = ""
+		wp.DumpDevice(dev)
+	} else {
+		if wp.ProcessedDevices.Get(dev.Parent){
+			wp.DumpDevice(dev)
+		} else {
+			wp.DeferredDevices = append(wp.DeferredDevices,dev.Id)
+		}
+	}
 }
 
 
@@ -100,9 +138,7 @@ func (wp *WurflProcessor) DumpDevice(dev *Device) {
 				wp.OutFile.WriteString(Cap.Name)
 				wp.OutFile.WriteString("`")
 				wp.OutFile.WriteString(":")
-				wp.OutFile.WriteString("`")
-				wp.OutFile.WriteString(Cap.Value)
-				wp.OutFile.WriteString("`")
+				wp.OutFile.WriteString(GoLiteral(wurflgo.ConvertCapability(Cap.Name,Cap.Value,wp.Schema)))
 				if i < len(grp.Capabilities){
 					wp.OutFile.WriteString(",")
 				}
@@ -119,18 +155,53 @@ func (wp *WurflProcessor) DumpDevice(dev *Device) {
 }
 
 
-func (wp *WurflProcessor)ProcessDeferredDevices(){
+// GoLiteral writes a converted capability value as a Go literal of the
+// same type, so the generated map holds typed values.
+func GoLiteral(value interface{}) string{
+	switch v := value.(type){
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		f := strconv.FormatFloat(v,'f',-1,64)
+		if !strings.Contains(f,"."){
+			f += ".0"
+		}
+		return f
+	case string:
+		if strings.Contains(v,"`"){
+			return strconv.Quote(v)
+		}
+		return "`" + v + "`"
+	}
+	return strconv.Quote(fmt.Sprint(value))
+}
+
+// ProcessDeferredDevices dumps the devices whose parent came later in the
+// input. It gives up once a whole pass over the remaining devices dumps
+// nothing, which happens when a fall_back is missing or loops.
+func (wp *WurflProcessor)ProcessDeferredDevices() error{
 	fmt.Println("Processing Deferred Devices...")
+	stalled := 0
 	for len(wp.DeferredDevices) > 0{
+		if stalled == len(wp.DeferredDevices){
+			orphans := append([]string(nil),wp.DeferredDevices...)
+			sort.Strings(orphans)
+			return fmt.Errorf("Unresolved fall_back devices for %s, run with -validate for details",strings.Join(orphans,", "))
+		}
 		devId := wp.DeferredDevices[0]
 		dev := wp.DeviceList[devId]
 		if wp.ProcessedDevices.Get(dev.Parent){
 			wp.DumpDevice(dev)
 			wp.DeferredDevices = wp.DeferredDevices[1:len(wp.DeferredDevices)]
+			stalled = 0
 		} else {
 			wp.DeferredDevices = append(wp.DeferredDevices[1:len(wp.DeferredDevices)],devId)
+			stalled++
 		}
 	}
+	return nil
 }
 
 func (wp *WurflProcessor) DumpHeader() {
@@ -193,18 +264,118 @@ func (set *StringSet) Remove(i string) {
 	delete(set.Set, i)
 }
 
+// WriteSnapshot loads infile into a wurflgo engine and saves it as a
+// binary snapshot, to be read back with wurflgo.LoadSnapshot.
+func WriteSnapshot(groups string, infile string, outfile string, patchFiles []string) error{
+	in,err := os.Open(infile)
+	if err != nil{
+		return err
+	}
+	defer in.Close()
+	patches := []io.Reader{}
+	for _,patch := range patchFiles{
+		f,err := os.Open(patch)
+		if err != nil{
+			return err
+		}
+		defer f.Close()
+		patches = append(patches,f)
+	}
+	e := wurflgo.NewEngine(nil)
+	if err = e.LoadXML(in,&wurflgo.LoadOptions{Groups: strings.Split(groups,",")},patches...); err != nil{
+		return err
+	}
+	out,err := os.Create(outfile)
+	if err != nil{
+		return err
+	}
+	if err = e.WriteSnapshot(out); err != nil{
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Validate reads infile and its patches and checks the device hierarchy
+// with wurflgo.Validate.
+func Validate(infile string, patchFiles []string) (*wurflgo.ValidationReport, error){
+	in,err := os.Open(infile)
+	if err != nil{
+		return nil,err
+	}
+	defer in.Close()
+	patches := []io.Reader{}
+	for _,patch := range patchFiles{
+		f,err := os.Open(patch)
+		if err != nil{
+			return nil,err
+		}
+		defer f.Close()
+		patches = append(patches,f)
+	}
+	return wurflgo.ValidateXML(in,patches...)
+}
+
+// PrintError reports err, naming the patch file of every conflict when
+// the patches could not be applied.
+func PrintError(err error, patches []string){
+	if pe, ok := err.(*wurflgo.PatchError); ok{
+		fmt.Println("An Error Occured while applying patches")
+		for _,c := range pe.Conflicts{
+			fmt.Printf("%s: device %s: %s\n",patches[c.Patch],c.DeviceId,c.Reason)
+		}
+		return
+	}
+	fmt.Printf("An Error Occured %s\n",err.Error())
+}
+
 func main() {
 	grp := flag.String("groups","product_info","list of groups separated by commas")
 	infile := flag.String("input","wurfl.xml","Path to the xml file")
 	outfile := flag.String("output","wurfl.go","Path to the output file")
+	format := flag.String("format","go","Output format: go for generated source, snapshot for a binary snapshot")
+	patch := flag.String("patch","","list of wurfl_patch.xml files separated by commas, applied in order")
+	validate := flag.Bool("validate",false,"Check the device hierarchy of the input and its patches and print a report instead of writing output")
 	flag.Parse()
-	wp,err := NewWurflProcessor(*grp,*infile,*outfile)
+	if *format != "go" && *format != "snapshot"{
+		fmt.Printf("Unknown format %s, expected go or snapshot\n",*format)
+		os.Exit(1)
+	}
+	patches := []string{}
+	if *patch != ""{
+		patches = strings.Split(*patch,",")
+	}
+	if *validate{
+		report,err := Validate(*infile,patches)
+		if err != nil{
+			PrintError(err,patches)
+			os.Exit(1)
+		}
+		fmt.Println(report)
+		if !report.OK(){
+			os.Exit(1)
+		}
+		return
+	}
+	if *format == "snapshot"{
+		fmt.Println("Please wait processing input file..")
+		if err := WriteSnapshot(*grp,*infile,*outfile,patches); err != nil{
+			PrintError(err,patches)
+			os.Exit(1)
+		}
+		fmt.Println("Snapshot saved to ",*outfile)
+		return
+	}
+	wp,err := NewWurflProcessor(*grp,*infile,*outfile,patches)
 	if err != nil{
-		fmt.Println("An Error Occured %s",err.Error())
+		fmt.Printf("An Error Occured %s\n",err.Error())
 		return
 	}
 	fmt.Println("Please wait processing input file..")
-	wp.Process()
+	if err = wp.Process(); err != nil{
+		PrintError(err,patches)
+		os.Exit(1)
+	}
 	//fmt.Println(*grp)
 	//fmt.Println(*infile)
 	fmt.Println("Output saved to ",*outfile)
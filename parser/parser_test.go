@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/srinathgs/wurflgo"
+)
+
+const testInput = "../testdata/wurfl.xml"
+
+func writeFile(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestValidate runs the checks of parser -validate.
+func TestValidate(t *testing.T) {
+	data, err := os.ReadFile(testInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Validate(testInput, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(data), "<device "); report.Devices != n {
+		t.Errorf("got %d devices, want %d", report.Devices, n)
+	}
+	if report.Count(wurflgo.IssueUnknownReference) != len(report.Issues) {
+		t.Errorf("testdata: got issues other than unknown references:\n%v", report)
+	}
+
+	broken := strings.Replace(string(data), `fall_back="generic_web_browser"/>`, `fall_back="generic_crawler"/>`, 1)
+	report, err = Validate(writeFile(t, "wurfl.xml", broken), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.OK() || report.Count(wurflgo.IssueMissingParent) != 1 {
+		t.Errorf("missing parent: got\n%v", report)
+	}
+
+	patch := writeFile(t, "patch.xml", `<wurfl_patch><devices><device id="test_phone" user_agent="TestPhone/1.0" fall_back="generic_phone"/></devices></wurfl_patch>`)
+	var patchErr *wurflgo.PatchError
+	if _, err = Validate(testInput, []string{patch}); !errors.As(err, &patchErr) || len(patchErr.Conflicts) != 1 {
+		t.Errorf("conflicting patch: got error %v", err)
+	}
+	if _, err = Validate(filepath.Join(t.TempDir(), "missing.xml"), nil); err == nil {
+		t.Error("missing input: no error")
+	}
+}
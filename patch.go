@@ -0,0 +1,170 @@
+package wurflgo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PatchConflict describes one problem found while applying a patch.
+type PatchConflict struct {
+	// Patch is the position of the patch in the list given to ReadXML,
+	// starting at 0.
+	Patch    int
+	DeviceId string
+	Reason   string
+}
+
+func (c PatchConflict) String() string {
+	return fmt.Sprintf("patch %d: device %s: %s", c.Patch, c.DeviceId, c.Reason)
+}
+
+// PatchError is returned when one or more patches could not be applied.
+// No devices are loaded in that case.
+type PatchError struct {
+	Conflicts []PatchConflict
+}
+
+func (e *PatchError) Error() string {
+	lines := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		lines[i] = c.String()
+	}
+	return "Conflicting patches:\n\t" + strings.Join(lines, "\n\t")
+}
+
+// patchDevice is a <device> element of wurfl_patch.xml. Attributes that
+// are left out keep the value of the device being patched.
+type patchDevice struct {
+	Id               string            `xml:"id,attr"`
+	FallBack         *string           `xml:"fall_back,attr"`
+	UserAgent        *string           `xml:"user_agent,attr"`
+	ActualDeviceRoot *bool             `xml:"actual_device_root,attr"`
+	Groups           []CapabilityGroup `xml:"group"`
+}
+
+// applyPatches layers patches over devices, in order. A patch may add
+// devices, override capabilities of existing ones and move them to
+// another fall_back.
+func applyPatches(devices []*DeviceDefinition, patches []io.Reader) ([]*DeviceDefinition, error) {
+	byId := make(map[string]*DeviceDefinition, len(devices))
+	for _, dev := range devices {
+		byId[dev.Id] = dev
+	}
+	conflicts := []PatchConflict{}
+	for i, patch := range patches {
+		seen := NewStringSet()
+		err := eachDeviceElement(patch, func(dec *xml.Decoder, se *xml.StartElement) error {
+			pd := new(patchDevice)
+			if err := dec.DecodeElement(pd, se); err != nil {
+				return err
+			}
+			conflict := func(format string, args ...interface{}) {
+				conflicts = append(conflicts, PatchConflict{i, pd.Id, fmt.Sprintf(format, args...)})
+			}
+			if pd.Id == "" {
+				conflict("device without an id")
+				return nil
+			}
+			if !seen.Add(pd.Id) {
+				conflict("listed more than once in the same patch")
+				return nil
+			}
+			dev, found := byId[pd.Id]
+			if !found {
+				if pd.UserAgent == nil || pd.FallBack == nil {
+					conflict("new device needs both user_agent and fall_back")
+					return nil
+				}
+				dev = &DeviceDefinition{Id: pd.Id}
+				byId[pd.Id] = dev
+				devices = append(devices, dev)
+			}
+			if pd.UserAgent != nil {
+				dev.UserAgent = *pd.UserAgent
+			}
+			if pd.FallBack != nil {
+				dev.FallBack = *pd.FallBack
+				if dev.FallBack == "root" {
+					dev.FallBack = ""
+				}
+			}
+			if pd.ActualDeviceRoot != nil {
+				dev.ActualDeviceRoot = *pd.ActualDeviceRoot
+			}
+			for _, grp := range pd.Groups {
+				dev.mergeGroup(grp)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("patch %d: %w", i, err)
+		}
+		conflicts = append(conflicts, checkPatched(i, seen, byId, devices)...)
+	}
+	if len(conflicts) > 0 {
+		return nil, &PatchError{conflicts}
+	}
+	return devices, nil
+}
+
+// mergeGroup overrides the capabilities of dev with those of grp, adding
+// the group or capabilities that dev does not have yet.
+func (dev *DeviceDefinition) mergeGroup(grp CapabilityGroup) {
+	for gi := range dev.Groups {
+		if dev.Groups[gi].Id != grp.Id {
+			continue
+		}
+		own := &dev.Groups[gi]
+	next:
+		for _, c := range grp.Capabilities {
+			for ci := range own.Capabilities {
+				if own.Capabilities[ci].Name == c.Name {
+					own.Capabilities[ci].Value = c.Value
+					continue next
+				}
+			}
+			own.Capabilities = append(own.Capabilities, c)
+		}
+		return
+	}
+	dev.Groups = append(dev.Groups, grp)
+}
+
+// checkPatched reports the fall_back links and user agents that a patch
+// left broken on the devices it touched: unknown parents, cycles and user
+// agents claimed by two devices.
+func checkPatched(patch int, touched *StringSet, byId map[string]*DeviceDefinition, devices []*DeviceDefinition) []PatchConflict {
+	conflicts := []PatchConflict{}
+	uas := make(map[string]string)
+	for _, dev := range devices {
+		if dev.UserAgent != "" && !touched.Get(dev.Id) {
+			uas[dev.UserAgent] = dev.Id
+		}
+	}
+	for _, dev := range devices {
+		if !touched.Get(dev.Id) {
+			continue
+		}
+		if dev.FallBack != "" && byId[dev.FallBack] == nil {
+			conflicts = append(conflicts, PatchConflict{patch, dev.Id, "fall_back " + dev.FallBack + " does not exist"})
+		}
+		visited := NewStringSet()
+		for cur := dev; cur != nil && cur.FallBack != ""; cur = byId[cur.FallBack] {
+			if !visited.Add(cur.Id) {
+				conflicts = append(conflicts, PatchConflict{patch, dev.Id, "fall_back chain loops through " + cur.Id})
+				break
+			}
+		}
+		if dev.UserAgent == "" {
+			continue
+		}
+		if other, found := uas[dev.UserAgent]; found {
+			conflicts = append(conflicts, PatchConflict{patch, dev.Id, "user_agent is already used by " + other})
+			continue
+		}
+		uas[dev.UserAgent] = dev.Id
+	}
+	return conflicts
+}
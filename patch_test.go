@@ -0,0 +1,135 @@
+package wurflgo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readPatched(tb testing.TB, patches ...string) ([]*DeviceDefinition, error) {
+	tb.Helper()
+	readers := make([]io.Reader, len(patches))
+	for i, p := range patches {
+		readers[i] = strings.NewReader(p)
+	}
+	return ReadXML(bytes.NewReader(testXML(tb)), readers...)
+}
+
+func findDefinition(devices []*DeviceDefinition, id string) *DeviceDefinition {
+	for _, dev := range devices {
+		if dev.Id == id {
+			return dev
+		}
+	}
+	return nil
+}
+
+func capabilityValue(dev *DeviceDefinition, group, name string) (string, bool) {
+	for _, grp := range dev.Groups {
+		if grp.Id != group {
+			continue
+		}
+		for _, c := range grp.Capabilities {
+			if c.Name == name {
+				return c.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// TestReadXMLPatches layers two patches: the first adds a device, the
+// second overrides and extends it and an existing device.
+func TestReadXMLPatches(t *testing.T) {
+	devices, err := readPatched(t, `<wurfl_patch><devices>
+<device id="test_phone" user_agent="TestPhone/1.0" fall_back="generic_android">
+  <group id="product_info"><capability name="brand_name" value="Test"/></group>
+</device>
+</devices></wurfl_patch>`, `<wurfl_patch><devices>
+<device id="test_phone">
+  <group id="product_info"><capability name="brand_name" value="Acme"/><capability name="model_name" value="Phone"/></group>
+  <group id="display"><capability name="resolution_width" value="480"/></group>
+</device>
+<device id="generic_android" fall_back="generic_mobile">
+  <group id="display"><capability name="resolution_height" value="480"/></group>
+</device>
+</devices></wurfl_patch>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := findDefinition(devices, "test_phone")
+	if dev == nil {
+		t.Fatal("test_phone was not added")
+	}
+	if dev.UserAgent != "TestPhone/1.0" || dev.FallBack != "generic_android" {
+		t.Errorf("test_phone: got user agent %q and fall_back %q", dev.UserAgent, dev.FallBack)
+	}
+	android := findDefinition(devices, "generic_android")
+	if android.FallBack != "generic_mobile" || android.UserAgent != "DO_NOT_MATCH_GENERIC_ANDROID" {
+		t.Errorf("generic_android: got user agent %q and fall_back %q", android.UserAgent, android.FallBack)
+	}
+	for _, test := range []struct {
+		dev                *DeviceDefinition
+		group, name, value string
+	}{
+		// Overridden by the second patch.
+		{dev, "product_info", "brand_name", "Acme"},
+		// Added to a group the device has.
+		{dev, "product_info", "model_name", "Phone"},
+		// Added with a group the device does not have.
+		{dev, "display", "resolution_width", "480"},
+		// Merged into an existing device, keeping its other capabilities.
+		{android, "display", "resolution_height", "480"},
+		{android, "display", "resolution_width", "320"},
+		{android, "product_info", "device_os", "Android"},
+	} {
+		value, found := capabilityValue(test.dev, test.group, test.name)
+		if !found || value != test.value {
+			t.Errorf("%s: %s/%s = %q (found %v), want %q", test.dev.Id, test.group, test.name, value, found, test.value)
+		}
+	}
+}
+
+// TestReadXMLPatchConflicts checks that the conflicts of every patch are
+// reported together and that nothing is loaded.
+func TestReadXMLPatchConflicts(t *testing.T) {
+	devices, err := readPatched(t, `<wurfl_patch><devices>
+<device id="test_phone" user_agent="TestPhone/1.0" fall_back="generic_android"/>
+<device id="test_phone" user_agent="TestPhone/2.0"/>
+<device id="test_tablet" user_agent="TestTablet/1.0"/>
+</devices></wurfl_patch>`, `<wurfl_patch><devices>
+<device id="test_watch" user_agent="TestWatch/1.0" fall_back="generic_watch"/>
+<device id="test_bot" user_agent="Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)" fall_back="generic"/>
+<device id="generic_mobile" fall_back="generic_android"/>
+</devices></wurfl_patch>`)
+	if devices != nil {
+		t.Errorf("got %d devices along with the conflicts", len(devices))
+	}
+	var patchErr *PatchError
+	if !errors.As(err, &patchErr) {
+		t.Fatalf("got error %v, want a *PatchError", err)
+	}
+	want := []PatchConflict{
+		{0, "test_phone", "listed more than once in the same patch"},
+		{0, "test_tablet", "new device needs both user_agent and fall_back"},
+		{1, "generic_mobile", "fall_back chain loops through generic_mobile"},
+		{1, "test_watch", "fall_back generic_watch does not exist"},
+		{1, "test_bot", "user_agent is already used by googlebot"},
+	}
+	if !reflect.DeepEqual(patchErr.Conflicts, want) {
+		t.Errorf("got conflicts\n\t%v\nwant\n\t%v", patchErr.Conflicts, want)
+	}
+}
+
+// TestReadXMLPatchParseError checks that a malformed patch is reported
+// with its position.
+func TestReadXMLPatchParseError(t *testing.T) {
+	_, err := readPatched(t, `<wurfl_patch><devices/></wurfl_patch>`, `<wurfl_patch><devices><device id="x"></devices>`)
+	var patchErr *PatchError
+	if err == nil || errors.As(err, &patchErr) || !strings.HasPrefix(err.Error(), "patch 1: ") {
+		t.Errorf("got error %v, want a parse error of patch 1", err)
+	}
+}
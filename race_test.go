@@ -0,0 +1,205 @@
+package wurflgo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentMatch checks that matches running side by side give the
+// results a single goroutine gets. Run it with -race.
+func TestConcurrentMatch(t *testing.T) {
+	e := newTestEngine(t, nil)
+	want := map[string]string{}
+	for _, ua := range testUAs {
+		want[ua] = e.Match(ua).Id
+	}
+
+	fresh := newTestEngine(t, &EngineOptions{CacheSize: 4})
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < 200; n++ {
+				ua := testUAs[(g+n)%len(testUAs)]
+				if got := fresh.Match(ua).Id; got != want[ua] {
+					t.Errorf("Match(%q) = %s, want %s", ua, got, want[ua])
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+// TestMatchWhileChangingData matches from several goroutines while the
+// data and the chain are replaced and devices registered. Run it with
+// -race.
+func TestMatchWhileChangingData(t *testing.T) {
+	e := newTestEngine(t, &EngineOptions{CacheSize: 8})
+	var snapshot bytes.Buffer
+	if err := e.WriteSnapshot(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; ; n++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				ua := testUAs[(g+n)%len(testUAs)]
+				if e.Match(ua) == nil {
+					t.Errorf("Match(%q) = nil", ua)
+					return
+				}
+				if _, err := e.MatchE(ua); errors.Is(err, ErrNoDevice) {
+					t.Errorf("MatchE(%q): %s", ua, err)
+					return
+				}
+				if res := e.Explain(ua); res.Device == nil {
+					t.Errorf("Explain(%q) has no device", ua)
+					return
+				}
+				req := httptest.NewRequest("GET", "/", nil)
+				req.Header.Set("User-Agent", ua)
+				if e.MatchRequest(req) == nil {
+					t.Errorf("MatchRequest(%q) = nil", ua)
+					return
+				}
+			}
+		}(g)
+	}
+
+	for i := 0; i < 20; i++ {
+		var err error
+		switch i % 4 {
+		case 0:
+			err = e.ReloadXML(bytes.NewReader(testXML(t)), nil)
+		case 1:
+			err = e.ReloadSnapshot(bytes.NewReader(snapshot.Bytes()))
+		case 2:
+			rule := HandlerRule{Name: fmt.Sprintf("Test%dHandler", i), Before: "AndroidHandler", StartsWith: []string{fmt.Sprintf("Test%d/", i)}}
+			err = e.CustomizeChain(func(c *Chain) error {
+				h, err := rule.Compile()
+				if err != nil {
+					return err
+				}
+				return c.InsertBefore(rule.Before, h)
+			})
+		case 3:
+			err = e.RegisterDevice(fmt.Sprintf("test_%d", i), fmt.Sprintf("Test%d/1.0", i), false, nil, "generic")
+		}
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// TestRegisterWhileCustomizing registers devices while the chain is
+// edited, which files the devices again in a new dataset, and checks
+// that none of them is lost. Run it with -race.
+func TestRegisterWhileCustomizing(t *testing.T) {
+	e := newTestEngine(t, nil)
+	const devices = 30
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < devices; i++ {
+			if err := e.RegisterDevice(fmt.Sprintf("test_%d", i), fmt.Sprintf("Test%d/1.0", i), false, nil, "generic"); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for i := 0; i < devices; i++ {
+		rule := HandlerRule{Name: fmt.Sprintf("Test%dHandler", i), Before: "AndroidHandler", StartsWith: []string{fmt.Sprintf("Other%d/", i)}}
+		if err := e.CustomizeChain(func(c *Chain) error {
+			h, err := rule.Compile()
+			if err != nil {
+				return err
+			}
+			return c.InsertBefore(rule.Before, h)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+	for i := 0; i < devices; i++ {
+		if id := fmt.Sprintf("test_%d", i); e.Find(id) == nil {
+			t.Errorf("%s was lost", id)
+		}
+	}
+}
+
+// TestLoadSnapshotWhileCustomizing checks that a chain edit made while a
+// snapshot is loaded is kept. Run it with -race.
+func TestLoadSnapshotWhileCustomizing(t *testing.T) {
+	e := newTestEngine(t, nil)
+	var snapshot bytes.Buffer
+	if err := e.WriteSnapshot(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+	const edits = 20
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < edits; i++ {
+			if err := e.LoadSnapshot(bytes.NewReader(snapshot.Bytes())); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for i := 0; i < edits; i++ {
+		if err := e.CustomizeChain(func(c *Chain) error {
+			return c.InsertBefore("AndroidHandler", testRuleHandler(t, fmt.Sprintf("Test%dHandler", i)))
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+	for i := 0; i < edits; i++ {
+		if name := fmt.Sprintf("Test%dHandler", i); e.Chain().Handler(name) == nil {
+			t.Errorf("%s was lost", name)
+		}
+	}
+}
+
+// TestLoadWhileSettingRules loads data and registers devices while the
+// handler rules are replaced. Run it with -race.
+func TestLoadWhileSettingRules(t *testing.T) {
+	e := newTestEngine(t, nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			rules := []HandlerRule{testSamsungRule}
+			if i%2 == 1 {
+				rules = nil
+			}
+			if err := e.SetHandlerRules(rules); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		if err := e.RegisterDevice(fmt.Sprintf("test_%d", i), fmt.Sprintf("Test%d/1.0", i), false, nil, "generic"); err != nil {
+			t.Fatal(err)
+		}
+		if err := e.LoadXML(bytes.NewReader(testXML(t)), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
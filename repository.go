@@ -1,17 +1,18 @@
 package wurflgo
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 //import "fmt"
 
-var chain = NewChain()
-
 func GetChain() *Chain {
-	return chain
+	return defaultEngine.Chain()
 }
 
 func GetUtil() *Util {
-	return util
+	return defaultEngine.Util()
 }
 
 type StringSet struct {
@@ -37,6 +38,9 @@ func (set *StringSet) Remove(i string) {
 	delete(set.Set, i)
 }
 
+// Device is a registered WURFL device. Capabilities holds only the
+// capabilities the device sets itself; the others are inherited from its
+// Parent. Value, Source and AllCapabilities resolve inherited values.
 type Device struct {
 	Id               string
 	UA               string
@@ -44,15 +48,26 @@ type Device struct {
 	Children         *StringSet
 	ActualDeviceRoot bool
 	Capabilities     map[string]interface{}
+
+	// flat holds the own and inherited capabilities of the device when
+	// the repository keeps flattened capabilities.
+	flat map[string]interface{}
 }
 
+var ErrUnregisteredParent = errors.New("Unregistered Parent Device")
+
 type Repository struct {
 	devices map[string]*Device
+	flatten bool
+	// types holds the type of every capability name registered, so a
+	// capability gets the same type on every device.
+	types CapabilitySchema
 }
 
 func NewRepository() *Repository {
 	r := new(Repository)
 	r.devices = make(map[string]*Device)
+	r.types = make(CapabilitySchema)
 	return r
 }
 
@@ -60,52 +75,65 @@ func (r *Repository) find(id string) *Device {
 	return r.devices[id]
 }
 
+func (r *Repository) count() int {
+	return len(r.devices)
+}
+
 func (r *Repository) register(id, ua string, actualDeviceRoot bool, capabilities map[string]interface{}, parent string) error {
 	dev := new(Device)
 	dev.Id = id
 	dev.UA = ua
 	dev.Children = NewStringSet()
-	dev.Capabilities = make(map[string]interface{})
-	if parent == "" {
-		dev.Capabilities = capabilities
-	} else {
+	dev.Capabilities = capabilities
+	if parent != "" {
 		parentDevice, found := r.devices[parent]
 		if found == true {
-			for k := range parentDevice.Capabilities {
-				dev.Capabilities[k] = parentDevice.Capabilities[k]
-			}
-			for k := range capabilities {
-				dev.Capabilities[k] = capabilities[k]
-			}
 			dev.Parent = parentDevice
 			parentDevice.Children.Add(dev.Id)
 		} else {
-			//fmt.Println(dev.Id)
-			return errors.New("Unregistered Parent Device")
+			return fmt.Errorf("%w %s of %s", ErrUnregisteredParent, parent, id)
+		}
+	}
+	if r.flatten {
+		dev.flat = make(map[string]interface{})
+		if dev.Parent != nil {
+			for k := range dev.Parent.flat {
+				dev.flat[k] = dev.Parent.flat[k]
+			}
+		}
+		for k := range capabilities {
+			dev.flat[k] = capabilities[k]
+		}
+	}
+	for name, value := range capabilities {
+		if _, found := r.types[name]; !found && value != "" {
+			r.types[name] = typeOf(value)
 		}
 	}
 	r.devices[dev.Id] = dev
-	chain.Filter(dev.UA, dev.Id)
 	return nil
 }
 
-var Repo = NewRepository()
-
 func RegisterDevice(id, ua string, actualDeviceRoot bool, capabilities map[string]interface{}, parent string) error {
-	return Repo.register(id, ua, actualDeviceRoot, capabilities, parent)
+	return defaultEngine.RegisterDevice(id, ua, actualDeviceRoot, capabilities, parent)
 }
 
 func Match(ua string) *Device {
-	m := chain.Match(ua)
-	//fmt.Println(m)
-	return Repo.find(m)
+	return defaultEngine.Match(ua)
+}
+
+func MatchE(ua string) (*Device, error) {
+	return defaultEngine.MatchE(ua)
 }
 
 func Find(id string) *Device {
-	return Repo.find(id)
+	return defaultEngine.Find(id)
 }
 
-func init() {
+// NewDefaultChain builds the standard WURFL handler chain, with every
+// handler sharing u.
+func NewDefaultChain(u *Util) *Chain {
+	chain := NewChainWithUtil(u)
 	genericNormalizers := CreateGenericNormalizers()
 	chain.AddHandler(NewJavaMidletHandler(genericNormalizers))
 	chain.AddHandler(NewSmartTVHandler(genericNormalizers))
@@ -184,6 +212,7 @@ func init() {
 	// All other requests.
 	chain.AddHandler(NewCatchAllHandler(genericNormalizers))
 
+	return chain
 }
 
 func CreateGenericNormalizers() *UserAgentNormalizer {
@@ -0,0 +1,118 @@
+package wurflgo
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DefaultUAHeaders lists the request headers that may carry a user agent,
+// most informative first. Proxies and transcoders such as Opera Mini, UC
+// Browser and Skyfire send their own User-Agent and pass the handset's in
+// one of the other headers.
+var DefaultUAHeaders = []string{
+	"X-Device-User-Agent",
+	"X-Original-User-Agent",
+	"X-OperaMini-Phone-UA",
+	"X-UCBrowser-Device-UA",
+	"Device-Stock-UA",
+	"X-Skyfire-Phone",
+	"X-Bolt-Phone-UA",
+	"User-Agent",
+}
+
+// UAProfHeader carries the URL of the handset's UAProf profile, which
+// the uaprof, uaprof2 and uaprof3 capabilities hold.
+const UAProfHeader = "X-Wap-Profile"
+
+// RequestUA returns the user agent MatchRequest would match for r: the
+// value of the first of the engine's UA headers that is set. A reduced
+// User-Agent header is completed from the request's client hints, see
+// ClientHints.UserAgent.
+func (e *Engine) RequestUA(r *http.Request) string {
+	for _, h := range e.uaHeaders {
+		ua := strings.TrimSpace(r.Header.Get(h))
+		if ua == "" || ua == "?" {
+			continue
+		}
+		if http.CanonicalHeaderKey(h) == "User-Agent" && !e.ignoreClientHints {
+			if ch := ParseClientHints(r.Header); ch != nil {
+				ua = ch.UserAgent(ua)
+			}
+		}
+		return ua
+	}
+	return ""
+}
+
+// MatchRequest matches the most informative user agent of an HTTP
+// request, see RequestUA. When that only leads to a generic device and
+// the request has an X-Wap-Profile header naming a known UAProf URL, the
+// device with that profile is returned instead.
+func (e *Engine) MatchRequest(r *http.Request) *Device {
+	return e.matchRequest(r, e.RequestUA(r))
+}
+
+func (e *Engine) matchRequest(r *http.Request, ua string) *Device {
+	ds := e.current()
+	ds.rlock()
+	defer ds.mu.RUnlock()
+	profile := strings.Trim(r.Header.Get(UAProfHeader), "\" ")
+	// Without a profile the result, error included, is the one of
+	// MatchE, which shares the cache key.
+	key := ua
+	if profile != "" {
+		key = ua + "\x00" + profile
+	}
+	if dev, _, found := e.cache.get(ds, key); found {
+		return dev
+	}
+	dev, err := ds.matchRequest(e.util, ua, profile)
+	e.cache.add(ds, key, dev, err)
+	return dev
+}
+
+// matchRequest returns the device for ua and the error of resolve, or
+// the device of profile and no error.
+func (ds *dataset) matchRequest(u *Util, ua, profile string) (*Device, error) {
+	id := ds.chain.Match(ua)
+	dev, err := ds.resolve(u, ua, id)
+	if err == nil && !isGenericId(id) {
+		return dev, nil
+	}
+	if profile != "" {
+		if byProfile := ds.repo.find(ds.uaprof[profile]); byProfile != nil {
+			return byProfile, nil
+		}
+	}
+	return dev, err
+}
+
+func MatchRequest(r *http.Request) *Device {
+	return defaultEngine.MatchRequest(r)
+}
+
+func isGenericId(id string) bool {
+	switch id {
+	case GENERIC, GENERIC_MOBILE, GENERIC_WEB_BROWSER, GENERIC_XHTML:
+		return true
+	}
+	return false
+}
+
+// indexUAProf maps the UAProf URLs of the repository's devices to their
+// ids. A URL set by several devices maps to the one closest to the root.
+func (r *Repository) indexUAProf() map[string]string {
+	index := make(map[string]string)
+	for _, dev := range r.ordered() {
+		for _, name := range []string{"uaprof", "uaprof2", "uaprof3"} {
+			url, ok := dev.Capabilities[name].(string)
+			if !ok || url == "" {
+				continue
+			}
+			if _, found := index[url]; !found {
+				index[url] = dev.Id
+			}
+		}
+	}
+	return index
+}
@@ -0,0 +1,116 @@
+package wurflgo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	testUAProf  = "http://wap.samsungmobile.com/uaprof/GT-I9300.xml"
+	testSagemUA = "SAGEM-myX5-2/1.0 Profile/MIDP-2.0 Configuration/CLDC-1.0"
+	testOperaUA = "Opera/9.80 (J2ME/MIDP; Opera Mini/9.80 (S60; SymbOS; Opera Mobi/23.348; U; en) Presto/2.5.25 Version/10.54"
+)
+
+func testRequest(headers map[string]string) *http.Request {
+	req := httptest.NewRequest("GET", "/", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	return req
+}
+
+// TestMatchRequestKeepsFallback checks that MatchE reports the fallback
+// of a user agent whose result MatchRequest cached first.
+func TestMatchRequestKeepsFallback(t *testing.T) {
+	uncached := newTestEngine(t, nil)
+	e := newTestEngine(t, &EngineOptions{CacheSize: 100})
+	for _, ua := range testUAs {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("User-Agent", ua)
+		e.MatchRequest(req)
+
+		wantDev, wantErr := uncached.MatchE(ua)
+		dev, err := e.MatchE(ua)
+		if dev != nil && wantDev != nil && dev.Id != wantDev.Id {
+			t.Errorf("MatchE(%q) = %s, want %s", ua, dev.Id, wantDev.Id)
+		}
+		var fb, wantFb *FallbackError
+		if errors.As(err, &fb) != errors.As(wantErr, &wantFb) || (fb != nil && *fb != *wantFb) {
+			t.Errorf("MatchE(%q) error = %v, want %v", ua, err, wantErr)
+		}
+	}
+	if hits := e.CacheStats().Hits; hits == 0 {
+		t.Errorf("MatchE did not use the results of MatchRequest")
+	}
+}
+
+// TestRequestUA checks that the headers of proxies and transcoders take
+// precedence over User-Agent.
+func TestRequestUA(t *testing.T) {
+	e := newTestEngine(t, &EngineOptions{IgnoreClientHints: true})
+	for _, test := range []struct {
+		headers map[string]string
+		want    string
+	}{
+		{map[string]string{"User-Agent": "ua"}, "ua"},
+		{map[string]string{"User-Agent": "ua", "X-OperaMini-Phone-UA": "opera"}, "opera"},
+		{map[string]string{"User-Agent": "ua", "X-OperaMini-Phone-UA": "opera", "X-Original-User-Agent": "original"}, "original"},
+		{map[string]string{"User-Agent": "ua", "X-OperaMini-Phone-UA": "opera", "X-Original-User-Agent": "original", "X-Device-User-Agent": "device"}, "device"},
+		// Empty and unknown values are skipped.
+		{map[string]string{"User-Agent": "ua", "X-OperaMini-Phone-UA": "?", "X-Device-User-Agent": " "}, "ua"},
+		{map[string]string{"User-Agent": " ua "}, "ua"},
+		{map[string]string{}, ""},
+	} {
+		if got := e.RequestUA(testRequest(test.headers)); got != test.want {
+			t.Errorf("%v: got %q, want %q", test.headers, got, test.want)
+		}
+	}
+
+	e = newTestEngine(t, &EngineOptions{UAHeaders: []string{"X-Custom-UA"}})
+	if got := e.RequestUA(testRequest(map[string]string{"User-Agent": "ua", "X-Custom-UA": "custom"})); got != "custom" {
+		t.Errorf("UAHeaders: got %q, want custom", got)
+	}
+	if got := e.RequestUA(testRequest(map[string]string{"User-Agent": "ua"})); got != "" {
+		t.Errorf("UAHeaders: got %q for a header that is not listed", got)
+	}
+}
+
+func TestMatchRequestHeaders(t *testing.T) {
+	e := newTestEngine(t, nil)
+	req := testRequest(map[string]string{"User-Agent": testOperaUA, "X-OperaMini-Phone-UA": testSamsungUA})
+	if dev := e.MatchRequest(req); dev.Id != "samsung_gt_i9300_ver1" {
+		t.Errorf("Opera Mini: got %s, want samsung_gt_i9300_ver1", dev.Id)
+	}
+}
+
+// TestMatchRequestUAProf checks that X-Wap-Profile only replaces generic
+// devices, and that results with and without a profile are cached apart.
+func TestMatchRequestUAProf(t *testing.T) {
+	e := newTestEngine(t, &EngineOptions{CacheSize: 100})
+	generic := e.Match(testSagemUA).Id
+	if !isGenericId(generic) {
+		t.Fatalf("%q matches %s, want a generic device", testSagemUA, generic)
+	}
+	for _, test := range []struct {
+		headers map[string]string
+		want    string
+	}{
+		{map[string]string{"User-Agent": testSagemUA, "X-Wap-Profile": `"` + testUAProf + `"`}, "samsung_gt_i9300_ver1"},
+		{map[string]string{"User-Agent": testSagemUA, "X-Wap-Profile": testUAProf}, "samsung_gt_i9300_ver1"},
+		{map[string]string{"User-Agent": testSagemUA}, generic},
+		{map[string]string{"User-Agent": testSagemUA, "X-Wap-Profile": "http://example.com/unknown.xml"}, generic},
+		{map[string]string{"User-Agent": "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", "X-Wap-Profile": testUAProf}, "googlebot"},
+	} {
+		// Twice, the second time from the cache.
+		for i := 0; i < 2; i++ {
+			if dev := e.MatchRequest(testRequest(test.headers)); dev.Id != test.want {
+				t.Errorf("%v: got %s, want %s", test.headers, dev.Id, test.want)
+			}
+		}
+	}
+	if e.CacheStats().Hits == 0 {
+		t.Error("no cache hits")
+	}
+}
@@ -0,0 +1,335 @@
+package wurflgo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// HandlerRule describes a handler that only differs from the others in
+// the user agents it claims, its normalizers, the tolerance of its
+// conclusive match and its recovery devices. Rules are usually read from
+// a JSON file with LoadHandlerRules and given to NewEngine through
+// EngineOptions.HandlerRules:
+//
+//	{"handlers": [{
+//		"name": "SagemHandler",
+//		"starts_with": ["Sagem", "SAGEM"],
+//		"skip_desktop": true,
+//		"tolerance": "first_slash"
+//	}, {
+//		"name": "AcmeHandler",
+//		"before": "AndroidHandler",
+//		"contains": ["acme"],
+//		"ignore_case": true,
+//		"normalizers": ["android"],
+//		"tolerance": "second_slash",
+//		"recovery": [{"contains": "tablet", "id": "acme_tab_ver1"}],
+//		"recovery_default": "acme_phone_ver1"
+//	}]}
+type HandlerRule struct {
+	// Name is the name of the handler in snapshots and match reports. A
+	// rule named after a handler of the chain replaces it.
+	Name string `json:"name"`
+
+	// Before and After place the handler in the chain, next to the
+	// handler of that name. At most one of them may be set. A rule
+	// replacing a handler takes its place otherwise, and a new handler is
+	// put just before CatchAllHandler.
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+
+	// The handler claims a user agent that starts with any of StartsWith,
+	// contains any of Contains or contains all of ContainsAll, unless it
+	// contains any of Excludes. IgnoreCase compares them all without
+	// regard to case, the recovery rules included. SkipDesktop leaves
+	// desktop browsers to the handlers further down the chain.
+	StartsWith  []string `json:"starts_with,omitempty"`
+	Contains    []string `json:"contains,omitempty"`
+	ContainsAll []string `json:"contains_all,omitempty"`
+	Excludes    []string `json:"excludes,omitempty"`
+	IgnoreCase  bool     `json:"ignore_case,omitempty"`
+	SkipDesktop bool     `json:"skip_desktop,omitempty"`
+
+	// Normalizers names the specific normalizers applied after the
+	// generic ones: android, chrome, firefox, htcmac, kindle, konqueror,
+	// lg, lgplus, msie, opera, safari or webos.
+	Normalizers []string `json:"normalizers,omitempty"`
+
+	// Tolerance is how long a prefix the conclusive RIS match must share
+	// with the user agent: first_slash (the default), second_slash,
+	// first_space, length for the whole user agent, or a number of
+	// bytes.
+	Tolerance string `json:"tolerance,omitempty"`
+
+	// Recovery is tried in order when the conclusive match finds nothing;
+	// the first rule whose text the user agent contains gives the device.
+	// RecoveryDefault is used when none does.
+	Recovery        []RecoveryRule `json:"recovery,omitempty"`
+	RecoveryDefault string         `json:"recovery_default,omitempty"`
+}
+
+// RecoveryRule picks the device Id for the user agents containing
+// Contains.
+type RecoveryRule struct {
+	Contains string `json:"contains"`
+	Id       string `json:"id"`
+}
+
+// ruleNormalizers are the specific normalizers a rule may name.
+var ruleNormalizers = map[string]func() Normalizer{
+	"android":   func() Normalizer { return NewAndroid() },
+	"chrome":    func() Normalizer { return NewChrome() },
+	"firefox":   func() Normalizer { return NewFirefox() },
+	"htcmac":    func() Normalizer { return NewHTCMac() },
+	"kindle":    func() Normalizer { return NewKindle() },
+	"konqueror": func() Normalizer { return NewKonqueror() },
+	"lg":        func() Normalizer { return NewLG() },
+	"lgplus":    func() Normalizer { return NewLGPLUS() },
+	"msie":      func() Normalizer { return NewMSIE() },
+	"opera":     func() Normalizer { return NewOpera() },
+	"safari":    func() Normalizer { return NewSafari() },
+	"webos":     func() Normalizer { return NewWebOS() },
+}
+
+// LoadHandlerRules reads the rules of a JSON rules file, see HandlerRule,
+// and checks them with ValidateHandlerRules.
+func LoadHandlerRules(r io.Reader) ([]HandlerRule, error) {
+	var file struct {
+		Handlers []HandlerRule `json:"handlers"`
+	}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("Bad handler rules: %s", err)
+	}
+	if err := ValidateHandlerRules(file.Handlers); err != nil {
+		return nil, err
+	}
+	return file.Handlers, nil
+}
+
+// ValidateHandlerRules reports the first rule that cannot be compiled or
+// placed in the default chain.
+func ValidateHandlerRules(rules []HandlerRule) error {
+	return NewDefaultChain(NewUtil()).ApplyRules(rules)
+}
+
+// SetHandlerRules replaces the handler rules of the engine, see
+// EngineOptions.HandlerRules, and files the user agents of the loaded
+// devices again. The engine keeps its rules if the new ones are invalid.
+// Called on the staging engine of Reload, it reloads the rules together
+// with the data:
+//
+//	err := e.Reload(func(s *wurflgo.Engine) error {
+//		if err := s.SetHandlerRules(rules); err != nil {
+//			return err
+//		}
+//		return s.LoadXML(f, nil)
+//	})
+func (e *Engine) SetHandlerRules(rules []HandlerRule) error {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	staging := &Engine{engineConfig: e.engineConfig}
+	staging.rules, staging.rulesErr = rules, nil
+	if err := e.rechain(staging); err != nil {
+		return err
+	}
+	e.rules, e.rulesErr = rules, nil
+	return nil
+}
+
+// ApplyRules compiles the rules into handlers and puts them in the
+// chain, in order, so a rule may be placed next to an earlier one. The
+// chain is left untouched if any rule is invalid. Apply them before
+// filing user agents, which stay with the handler they were filed with.
+func (c *Chain) ApplyRules(rules []HandlerRule) error {
+	next := &Chain{Handlers: append([]Handlers{}, c.Handlers...), util: c.util}
+	if err := next.applyRules(rules); err != nil {
+		// next shares its handlers with c, so link them back.
+		c.relink()
+		return err
+	}
+	c.Handlers = next.Handlers
+	return nil
+}
+
+func (c *Chain) applyRules(rules []HandlerRule) error {
+	names := map[string]bool{}
+	for _, rule := range rules {
+		if names[rule.Name] {
+			return fmt.Errorf("Handler rule %s: defined twice", rule.Name)
+		}
+		names[rule.Name] = true
+		h, err := rule.Compile()
+		if err != nil {
+			return err
+		}
+		if err := c.placeRule(rule, h); err != nil {
+			return fmt.Errorf("Handler rule %s: %w", rule.Name, err)
+		}
+	}
+	c.relink()
+	return nil
+}
+
+// placeRule puts h where rule asks for.
+func (c *Chain) placeRule(rule HandlerRule, h Handlers) error {
+	switch {
+	case rule.Before != "" && rule.After != "":
+		return errors.New("both before and after are set")
+	case rule.Before == "" && rule.After == "" && c.index(rule.Name) >= 0:
+		return c.Replace(rule.Name, h)
+	}
+	if c.index(rule.Name) >= 0 {
+		if err := c.Remove(rule.Name); err != nil {
+			return err
+		}
+	}
+	switch {
+	case rule.Before != "":
+		return c.InsertBefore(rule.Before, h)
+	case rule.After != "":
+		return c.InsertAfter(rule.After, h)
+	case c.index("CatchAllHandler") >= 0:
+		return c.InsertBefore("CatchAllHandler", h)
+	}
+	return c.insert(len(c.Handlers), h)
+}
+
+// Compile builds the handler the rule describes.
+func (rule HandlerRule) Compile() (*RuleHandler, error) {
+	if rule.Name == "" {
+		return nil, errors.New("Handler rule without a name")
+	}
+	if len(rule.StartsWith)+len(rule.Contains)+len(rule.ContainsAll) == 0 {
+		return nil, fmt.Errorf("Handler rule %s: no starts_with, contains or contains_all", rule.Name)
+	}
+	norm := CreateGenericNormalizers()
+	for _, name := range rule.Normalizers {
+		newNormalizer, found := ruleNormalizers[name]
+		if !found {
+			return nil, fmt.Errorf("Handler rule %s: unknown normalizer %s", rule.Name, name)
+		}
+		norm = norm.AddNormalizer(newNormalizer())
+	}
+
+	h := &RuleHandler{rule: rule, fixed: -1}
+	switch rule.Tolerance {
+	case "", "first_slash", "second_slash", "first_space", "length":
+	default:
+		n, err := strconv.Atoi(rule.Tolerance)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("Handler rule %s: bad tolerance %s", rule.Name, rule.Tolerance)
+		}
+		h.fixed = n
+	}
+	for _, r := range rule.Recovery {
+		if r.Contains == "" || r.Id == "" {
+			return nil, fmt.Errorf("Handler rule %s: recovery needs contains and id", rule.Name)
+		}
+		h.recovery = append(h.recovery, RecoveryRule{h.fold([]string{r.Contains})[0], r.Id})
+		h.ConstantIds = append(h.ConstantIds, r.Id)
+	}
+	if rule.RecoveryDefault != "" {
+		h.ConstantIds = append(h.ConstantIds, rule.RecoveryDefault)
+	}
+	h.startsWith = h.fold(rule.StartsWith)
+	h.contains = h.fold(rule.Contains)
+	h.containsAll = h.fold(rule.ContainsAll)
+	h.excludes = h.fold(rule.Excludes)
+	h.Init(h, norm)
+	return h, nil
+}
+
+// RuleHandler is a handler compiled from a HandlerRule.
+type RuleHandler struct {
+	BaseHandler
+	// ConstantIds are the recovery device ids, for Validate.
+	ConstantIds []string
+
+	rule                                        HandlerRule
+	startsWith, contains, containsAll, excludes []string
+	recovery                                    []RecoveryRule
+	// fixed is the tolerance when the rule gives a number, -1 otherwise.
+	fixed int
+}
+
+func (h *RuleHandler) FixedIds() []string {
+	return h.ConstantIds
+}
+
+// fold lower-cases patterns when the rule ignores case.
+func (h *RuleHandler) fold(patterns []string) []string {
+	if !h.rule.IgnoreCase {
+		return patterns
+	}
+	folded := make([]string, len(patterns))
+	for i := range patterns {
+		folded[i] = strings.ToLower(patterns[i])
+	}
+	return folded
+}
+
+func (h *RuleHandler) Name() string {
+	return h.rule.Name
+}
+
+// fingerprint tells snapshots apart that were written with another rule
+// of the same name.
+func (h *RuleHandler) fingerprint() string {
+	rule, _ := json.Marshal(h.rule)
+	return string(rule)
+}
+
+// Rule returns the rule the handler was compiled from.
+func (h *RuleHandler) Rule() HandlerRule {
+	return h.rule
+}
+
+func (h *RuleHandler) CanHandle(ua string) bool {
+	if h.rule.SkipDesktop && h.util.IsDesktopBrowser(ua) {
+		return false
+	}
+	if h.rule.IgnoreCase {
+		ua = strings.ToLower(ua)
+	}
+	if h.util.CheckIfContainsAnyOf(ua, h.excludes) {
+		return false
+	}
+	return h.util.CheckIfStartsWithAnyOf(ua, h.startsWith) ||
+		h.util.CheckIfContainsAnyOf(ua, h.contains) ||
+		(len(h.containsAll) > 0 && h.util.CheckIfContainsAll(ua, h.containsAll))
+}
+
+func (h *RuleHandler) Tolerance(ua string) int {
+	switch {
+	case h.fixed >= 0:
+		return h.fixed
+	case h.rule.Tolerance == "second_slash":
+		return h.util.SecondSlash(ua)
+	case h.rule.Tolerance == "first_space":
+		return h.util.FirstSpace(ua)
+	case h.rule.Tolerance == "length":
+		return len(ua)
+	}
+	return h.util.FirstSlash(ua)
+}
+
+func (h *RuleHandler) ApplyRecoveryMatch(ua string) string {
+	if h.rule.IgnoreCase {
+		ua = strings.ToLower(ua)
+	}
+	for _, r := range h.recovery {
+		if strings.Contains(ua, r.Contains) {
+			return r.Id
+		}
+	}
+	if h.rule.RecoveryDefault != "" {
+		return h.rule.RecoveryDefault
+	}
+	return NO_MATCH
+}
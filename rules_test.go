@@ -0,0 +1,96 @@
+package wurflgo
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testSamsungUA = "Mozilla/5.0 (Linux; U; Android 4.0.4; en-gb; GT-I9300 Build/IMM76D) AppleWebKit/534.30 (KHTML, like Gecko) Version/4.0 Mobile Safari/534.30"
+
+var testSamsungRule = HandlerRule{Name: "SamsungRuleHandler", Before: "AndroidHandler", Contains: []string{"GT-I9300"}, Tolerance: "length"}
+
+func TestLoadHandlerRules(t *testing.T) {
+	rules, err := LoadHandlerRules(strings.NewReader(`{"handlers": [{"name": "SamsungRuleHandler", "before": "AndroidHandler", "contains": ["GT-I9300"], "tolerance": "length"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || !reflect.DeepEqual(rules[0], testSamsungRule) {
+		t.Errorf("got %+v, want %+v", rules, testSamsungRule)
+	}
+	for _, bad := range []string{
+		`{"handlers": [{"name": "X", "contains": ["x"], "unknown": true}]}`,
+		`{"handlers": [{"name": "X"}]}`,
+		`{"handlers": [{"name": "X", "contains": ["x"], "before": "NoSuchHandler"}]}`,
+		`{"handlers": [{"name": "X", "contains": ["x"], "tolerance": "half"}]}`,
+	} {
+		if _, err := LoadHandlerRules(strings.NewReader(bad)); err == nil {
+			t.Errorf("%s: no error", bad)
+		}
+	}
+}
+
+func TestNewEngineInvalidRules(t *testing.T) {
+	opts := &EngineOptions{HandlerRules: []HandlerRule{{Name: "X", Contains: []string{"x"}, Before: "NoSuchHandler"}}}
+	if _, err := NewEngineE(opts); !errors.Is(err, ErrUnknownHandler) {
+		t.Errorf("NewEngineE: got %v, want ErrUnknownHandler", err)
+	}
+	e := NewEngine(opts)
+	if err := e.LoadXML(bytes.NewReader(testXML(t)), nil); !errors.Is(err, ErrUnknownHandler) {
+		t.Errorf("LoadXML: got %v, want ErrUnknownHandler", err)
+	}
+	if err := e.RegisterDevice("generic", "", false, nil, ""); !errors.Is(err, ErrUnknownHandler) {
+		t.Errorf("RegisterDevice: got %v, want ErrUnknownHandler", err)
+	}
+
+	// Valid rules given by the reload are kept.
+	err := e.Reload(func(s *Engine) error {
+		if err := s.SetHandlerRules([]HandlerRule{testSamsungRule}); err != nil {
+			return err
+		}
+		return s.LoadXML(bytes.NewReader(testXML(t)), nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := e.Explain(testSamsungUA); res.Handler != testSamsungRule.Name {
+		t.Errorf("got handler %s, want %s", res.Handler, testSamsungRule.Name)
+	}
+	if err := e.RegisterDevice("test", "Test/1.0", false, nil, "generic"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSetHandlerRules(t *testing.T) {
+	e := newTestEngine(t, nil)
+	if err := e.SetHandlerRules([]HandlerRule{testSamsungRule}); err != nil {
+		t.Fatal(err)
+	}
+	if res := e.Explain(testSamsungUA); res.Handler != testSamsungRule.Name || res.DeviceId != "samsung_gt_i9300_ver1" {
+		t.Errorf("got %s by %s, want samsung_gt_i9300_ver1 by %s", res.DeviceId, res.Handler, testSamsungRule.Name)
+	}
+
+	if err := e.SetHandlerRules([]HandlerRule{{Name: "X"}}); err == nil {
+		t.Error("invalid rules: no error")
+	}
+	if res := e.Explain(testSamsungUA); res.Handler != testSamsungRule.Name {
+		t.Errorf("invalid rules replaced the old ones: got handler %s", res.Handler)
+	}
+
+	// A reload that does not set rules keeps them.
+	if err := e.ReloadXML(bytes.NewReader(testXML(t)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if res := e.Explain(testSamsungUA); res.Handler != testSamsungRule.Name {
+		t.Errorf("after ReloadXML: got handler %s", res.Handler)
+	}
+
+	if err := e.SetHandlerRules(nil); err != nil {
+		t.Fatal(err)
+	}
+	if res := e.Explain(testSamsungUA); res.Handler != "AndroidHandler" || res.DeviceId != "samsung_gt_i9300_ver1" {
+		t.Errorf("without rules: got %s by %s", res.DeviceId, res.Handler)
+	}
+}
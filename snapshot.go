@@ -0,0 +1,265 @@
+package wurflgo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// SnapshotVersion is the version of the snapshot layout written by
+// WriteSnapshot. Snapshots written with any other version are rejected,
+// since their handler tables may have been built with other normalizers
+// or handlers.
+const SnapshotVersion = 3
+
+const snapshotMagic = "WURFLGOS"
+
+var (
+	ErrNotSnapshot     = errors.New("Not a wurflgo snapshot")
+	ErrSnapshotVersion = errors.New("Unsupported snapshot version")
+)
+
+type snapshot struct {
+	// Chain is the fingerprint of the chain the tables were built with,
+	// see chainFingerprint.
+	Chain   []string
+	Devices []snapshotDevice
+	Tables  []snapshotTable
+}
+
+// snapshotDevice holds only the capabilities a device sets itself; the
+// rest are inherited from its fall_back again when the snapshot is loaded.
+type snapshotDevice struct {
+	Id               string
+	UA               string
+	Parent           string
+	ActualDeviceRoot bool
+	Capabilities     map[string]interface{}
+}
+
+// snapshotTable is one handler's normalized UAs, already sorted, with
+// the device id of each.
+type snapshotTable struct {
+	Handler string
+	Table   string
+	UAs     []string
+	Ids     []string
+}
+
+type handlerTable struct {
+	name    string
+	ids     map[string]string
+	ordered func() []string
+	restore func([]string)
+}
+
+// handlerTables lists the UA tables a handler keeps. Every handler has
+// the one returned by GetOrderedUAS; CatchAllHandler also keeps its
+// Mozilla tables apart.
+func handlerTables(h Handlers) []handlerTable {
+	tables := []handlerTable{{"", h.GetUASWithDeviceId(), h.GetOrderedUAS, h.SetOrderedUAS}}
+	if cah, ok := h.(*CatchAllHandler); ok {
+		tables = append(tables,
+			handlerTable{"Mozilla4", cah.Mozilla4UASWithDeviceId, cah.getMozilla4OrderedUAS, func(uas []string) { cah.Mozilla4OrderedUAS = uas }},
+			handlerTable{"Mozilla5", cah.Mozilla5UASWithDeviceId, cah.getMozilla5OrderedUAS, func(uas []string) { cah.Mozilla5OrderedUAS = uas }},
+		)
+	}
+	return tables
+}
+
+// WriteSnapshot writes the engine's devices and handler tables in a
+// compact binary form that LoadSnapshot restores without parsing XML or
+// normalizing user agents.
+func (e *Engine) WriteSnapshot(w io.Writer) error {
+	ds := e.current()
+	ds.rlock()
+	defer ds.mu.RUnlock()
+
+	snap := snapshot{}
+	for _, dev := range ds.repo.ordered() {
+		sd := snapshotDevice{
+			Id:               dev.Id,
+			UA:               dev.UA,
+			ActualDeviceRoot: dev.ActualDeviceRoot,
+			Capabilities:     dev.Capabilities,
+		}
+		if dev.Parent != nil {
+			sd.Parent = dev.Parent.Id
+		}
+		snap.Devices = append(snap.Devices, sd)
+	}
+	snap.Chain = chainFingerprint(ds.chain)
+	for _, h := range ds.chain.Handlers {
+		for _, t := range handlerTables(h) {
+			st := snapshotTable{Handler: HandlerName(h), Table: t.name, UAs: t.ordered()}
+			st.Ids = make([]string, len(st.UAs))
+			for i, ua := range st.UAs {
+				st.Ids[i] = t.ids[ua]
+			}
+			snap.Tables = append(snap.Tables, st)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(bw, binary.BigEndian, uint32(SnapshotVersion)); err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(bw).Encode(&snap); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// LoadSnapshot replaces the engine's data with a snapshot written by
+// WriteSnapshot. The engine is ready to match as soon as it returns. When
+// the snapshot was written by an engine with another handler chain, see
+// EngineOptions.HandlerRules and CustomizeChain, its user agents are
+// filed with the engine's chain again instead of restoring its tables.
+func (e *Engine) LoadSnapshot(r io.Reader) error {
+	e.reloadMu.Lock()
+	defer e.reloadMu.Unlock()
+	ds, err := e.readSnapshot(r)
+	if err != nil {
+		return err
+	}
+	e.data.Store(ds)
+	e.cache.purge()
+	return nil
+}
+
+// ReloadSnapshot swaps in the data of a snapshot. See Reload.
+func (e *Engine) ReloadSnapshot(r io.Reader) error {
+	return e.Reload(func(staging *Engine) error {
+		return staging.LoadSnapshot(r)
+	})
+}
+
+// LoadSnapshot loads a snapshot into the default engine.
+func LoadSnapshot(r io.Reader) error {
+	return defaultEngine.LoadSnapshot(r)
+}
+
+func (e *Engine) readSnapshot(r io.Reader) (*dataset, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != snapshotMagic {
+		return nil, ErrNotSnapshot
+	}
+	var version uint32
+	if err := binary.Read(br, binary.BigEndian, &version); err != nil {
+		return nil, ErrNotSnapshot
+	}
+	if version != SnapshotVersion {
+		return nil, fmt.Errorf("%w %d, expected %d", ErrSnapshotVersion, version, SnapshotVersion)
+	}
+	snap := snapshot{}
+	if err := gob.NewDecoder(br).Decode(&snap); err != nil {
+		return nil, err
+	}
+
+	if e.rulesErr != nil {
+		return nil, e.rulesErr
+	}
+	ds, err := e.newDataset()
+	if err != nil {
+		return nil, err
+	}
+	for _, sd := range snap.Devices {
+		if sd.Capabilities == nil {
+			sd.Capabilities = make(map[string]interface{})
+		}
+		if err := ds.repo.register(sd.Id, sd.UA, sd.ActualDeviceRoot, sd.Capabilities, sd.Parent); err != nil {
+			return nil, fmt.Errorf("%s: %s", sd.Id, err)
+		}
+	}
+	if !equalStrings(snap.Chain, chainFingerprint(ds.chain)) {
+		for _, sd := range snap.Devices {
+			ds.chain.Filter(sd.UA, sd.Id)
+		}
+		snap.Tables = nil
+	}
+	for _, st := range snap.Tables {
+		h := ds.chain.Handler(st.Handler)
+		if h == nil {
+			return nil, fmt.Errorf("%w: unknown handler %s", ErrNotSnapshot, st.Handler)
+		}
+		var table *handlerTable
+		tables := handlerTables(h)
+		for i := range tables {
+			if tables[i].name == st.Table {
+				table = &tables[i]
+				break
+			}
+		}
+		if table == nil || len(st.UAs) != len(st.Ids) {
+			return nil, fmt.Errorf("%w: bad table %s %s", ErrNotSnapshot, st.Handler, st.Table)
+		}
+		for i, ua := range st.UAs {
+			table.ids[ua] = st.Ids[i]
+		}
+		table.restore(st.UAs)
+	}
+	ds.chain.Freeze()
+	ds.uaprof = ds.repo.indexUAProf()
+	ds.frozen = true
+	return ds, nil
+}
+
+// ordered returns the devices of the repository with every device after
+// its fall_back, the order in which they can be registered again.
+func (r *Repository) ordered() []*Device {
+	devices := make([]*Device, 0, len(r.devices))
+	roots := []string{}
+	for id, dev := range r.devices {
+		if dev.Parent == nil {
+			roots = append(roots, id)
+		}
+	}
+	sort.Strings(roots)
+	queue := roots
+	for len(queue) > 0 {
+		dev := r.devices[queue[0]]
+		queue = queue[1:]
+		devices = append(devices, dev)
+		children := []string{}
+		for id := range dev.Children.Set {
+			children = append(children, id)
+		}
+		sort.Strings(children)
+		queue = append(queue, children...)
+	}
+	return devices
+}
+
+// chainFingerprint describes the handlers of c, in order, as far as they
+// decide which user agents they claim: their names, and the definitions
+// of the handlers built from data such as rules.
+func chainFingerprint(c *Chain) []string {
+	fingerprint := make([]string, len(c.Handlers))
+	for i, h := range c.Handlers {
+		fingerprint[i] = HandlerName(h)
+		if f, ok := h.(interface{ fingerprint() string }); ok {
+			fingerprint[i] += " " + f.fingerprint()
+		}
+	}
+	return fingerprint
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package wurflgo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func writeTestSnapshot(t *testing.T, e *Engine) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := e.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	e := newTestEngine(t, nil)
+	loaded := NewEngine(nil)
+	if err := loaded.LoadSnapshot(bytes.NewReader(writeTestSnapshot(t, e))); err != nil {
+		t.Fatal(err)
+	}
+	for _, ua := range testUAs {
+		want, got := e.Explain(ua), loaded.Explain(ua)
+		if got.Device.Id != want.Device.Id || got.Handler != want.Handler || got.Stage != want.Stage {
+			t.Errorf("%q: got %s by %s/%s, want %s by %s/%s", ua, got.Device.Id, got.Handler, got.Stage, want.Device.Id, want.Handler, want.Stage)
+		}
+	}
+}
+
+// TestSnapshotOtherChain loads a snapshot into an engine whose chain has
+// a handler the writing engine did not have.
+func TestSnapshotOtherChain(t *testing.T) {
+	snap := writeTestSnapshot(t, newTestEngine(t, nil))
+	samsung := "Mozilla/5.0 (Linux; U; Android 4.0.4; en-gb; GT-I9300 Build/IMM76D) AppleWebKit/534.30 (KHTML, like Gecko) Version/4.0 Mobile Safari/534.30"
+
+	for _, rule := range []HandlerRule{
+		{Name: "SamsungRuleHandler", Before: "AndroidHandler", Contains: []string{"GT-I9300"}, Tolerance: "length"},
+		// Same name, other user agents.
+		{Name: "SamsungRuleHandler", Before: "AndroidHandler", Contains: []string{"GT-I9300"}, Tolerance: "first_space"},
+	} {
+		e := NewEngine(&EngineOptions{HandlerRules: []HandlerRule{rule}})
+		if err := e.LoadSnapshot(bytes.NewReader(snap)); err != nil {
+			t.Fatal(err)
+		}
+		res := e.Explain(samsung)
+		if res.Handler != rule.Name || res.Device.Id != "samsung_gt_i9300_ver1" {
+			t.Errorf("tolerance %s: got %s by %s/%s, want samsung_gt_i9300_ver1 by %s", rule.Tolerance, res.Device.Id, res.Handler, res.Stage, rule.Name)
+		}
+		// Written by e, the snapshot restores the tables of its chain.
+		again := NewEngine(&EngineOptions{HandlerRules: []HandlerRule{rule}})
+		if err := again.LoadSnapshot(bytes.NewReader(writeTestSnapshot(t, e))); err != nil {
+			t.Fatal(err)
+		}
+		if got := again.Explain(samsung); got.Handler != res.Handler || got.Device.Id != res.Device.Id {
+			t.Errorf("tolerance %s: got %s by %s after a round trip, want %s by %s", rule.Tolerance, got.Device.Id, got.Handler, res.Device.Id, res.Handler)
+		}
+	}
+}
+
+func TestSnapshotVersion(t *testing.T) {
+	snap := writeTestSnapshot(t, newTestEngine(t, nil))
+	binary.BigEndian.PutUint32(snap[len(snapshotMagic):], SnapshotVersion-1)
+	if err := NewEngine(nil).LoadSnapshot(bytes.NewReader(snap)); !errors.Is(err, ErrSnapshotVersion) {
+		t.Errorf("got %v, want ErrSnapshotVersion", err)
+	}
+	if err := NewEngine(nil).LoadSnapshot(bytes.NewReader([]byte("<wurfl/>"))); !errors.Is(err, ErrNotSnapshot) {
+		t.Errorf("got %v, want ErrNotSnapshot", err)
+	}
+}
@@ -8,23 +8,25 @@ import (
 
 type Android struct{
 	UANormalizer
+	util *Util
+	handler *AndroidHandler
 }
 
-var androidHandler = NewAndroidHandler(NewAndroid())
-
-var htcMacHandler = NewHTCMacHandler(NewHTCMac())
-
-var webOSHandler = NewWebOSHandler(NewWebOS())
-
 func NewAndroid() *Android{
 	android := new(Android)
 	android.Regexp = `(Android)[ \-](\d\.\d)([^; \/\)]+)`
 	android.wordRx = regexp.MustCompile(android.Regexp)
+	android.handler = NewAndroidHandler(&Null{})
 	return android 
 }
 
+func (a *Android) SetUtil(u *Util) {
+	a.util = u
+	a.handler.SetUtil(u)
+}
+
 func (a *Android) Normalize(ua string) string{
-	ua = a.wordRx.ReplaceAllString(ua,`\1 \2`)
+	ua = a.wordRx.ReplaceAllString(ua,`${1} ${2}`)
 	skipNormalization := []string{
             "Opera Mini",
             "Opera Mobi",
@@ -34,9 +36,9 @@ func (a *Android) Normalize(ua string) string{
             "UCWEB7",
             "NetFrontLifeBrowser/2.2",
         }
-        if util.CheckIfContainsAnyOf(ua, skipNormalization) != true{
-        	model := androidHandler.GetAndroidModel(ua)
-        	version := androidHandler.GetAndroidVersion(ua,false)
+        if a.util.CheckIfContainsAnyOf(ua, skipNormalization) != true{
+        	model := a.handler.GetAndroidModel(ua)
+        	version := a.handler.GetAndroidVersion(ua,false)
         	if model != "" && version != ""{
         		prefix := version + " " + model + RIS_DELIMITER
         		return prefix + ua
@@ -93,15 +95,21 @@ func (ff *Firefox) firefoxWithMajorVersion(ua string) string{
 }
 
 type HTCMac struct{
-
+	handler *HTCMacHandler
 }
 
 func NewHTCMac() *HTCMac{
-	return new(HTCMac)
+	htc := new(HTCMac)
+	htc.handler = NewHTCMacHandler(&Null{})
+	return htc
+}
+
+func (htc *HTCMac) SetUtil(u *Util) {
+	htc.handler.SetUtil(u)
 }
 
 func (htc *HTCMac) Normalize(ua string) string {
-	model := htcMacHandler.GetHTCMacModel(ua)
+	model := htc.handler.GetHTCMacModel(ua)
 	if model != ""{
 		prefix := model + RIS_DELIMITER
 		return prefix + ua
@@ -110,18 +118,26 @@ func (htc *HTCMac) Normalize(ua string) string {
 }
 
 type Kindle struct{
-
+	util *Util
+	handler *AndroidHandler
 }
 
 func NewKindle() *Kindle{
-	return new(Kindle)
+	kindle := new(Kindle)
+	kindle.handler = NewAndroidHandler(&Null{})
+	return kindle
+}
+
+func (k *Kindle) SetUtil(u *Util) {
+	k.util = u
+	k.handler.SetUtil(u)
 }
 
 func (k *Kindle) Normalize(ua string) string{
-	if util.CheckIfContainsAll(ua,[]string{"Android", "Kindle Fire"}){
+	if k.util.CheckIfContainsAll(ua,[]string{"Android", "Kindle Fire"}){
 		
-        	model := androidHandler.GetAndroidModel(ua)
-        	version := androidHandler.GetAndroidVersion(ua,false)
+        	model := k.handler.GetAndroidModel(ua)
+        	version := k.handler.GetAndroidVersion(ua,false)
         	if model != "" && version != ""{
         		prefix := version + " " + model + RIS_DELIMITER
         		return prefix + ua
@@ -206,17 +222,22 @@ func (ms *MSIE) msieWithVersion(ua string) string{
 }
 
 type Opera struct{
-	
+	util *Util
 }
 
 func NewOpera() *Opera{
 	return new(Opera)
 }
 
+var operaNormalizerVersionRx = regexp.MustCompile(`Version/(\d+\.\d+)`)
+
+func (op *Opera) SetUtil(u *Util) {
+	op.util = u
+}
+
 func (op *Opera) Normalize(ua string) string{
-	if util.CheckIfStartsWith(ua,"Opera/9.80"){
-		matchRx := regexp.MustCompile(`Version/(\d+\.\d+)`)
-		matches := matchRx.FindStringSubmatch(ua)
+	if op.util.CheckIfStartsWith(ua,"Opera/9.80"){
+		matches := operaNormalizerVersionRx.FindStringSubmatch(ua)
 		if len(matches) > 0{
 			ua = strings.Replace(ua,"Opera/9.80","Opera/" + matches[1], -1)
 		}
@@ -239,16 +260,22 @@ func (s *Safari) Normalize(ua string) string{
 }
 
 type WebOS struct{
-
+	handler *WebOSHandler
 }
 
 func NewWebOS() *WebOS{
-	return new(WebOS)
+	webOS := new(WebOS)
+	webOS.handler = NewWebOSHandler(&Null{})
+	return webOS
+}
+
+func (w *WebOS) SetUtil(u *Util) {
+	w.handler.SetUtil(u)
 }
 
 func (w *WebOS) Normalize(ua string) string {
-	model := webOSHandler.GetWebOSModelVersion(ua)
-	OSVer := webOSHandler.GetWebOSVersion(ua)
+	model := w.handler.GetWebOSModelVersion(ua)
+	OSVer := w.handler.GetWebOSVersion(ua)
 	if model != "" && OSVer != ""{
 		prefix := model + " " + OSVer + RIS_DELIMITER
 		return prefix + ua
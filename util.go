@@ -4,19 +4,55 @@ import (
 	"regexp"
 	"strings"
 	"sort"
+	"sync"
 	"github.com/srinathgs/wurflgo/matcher"
 )
 
+// Util holds the keyword lists shared by the handlers. The lists are
+// compiled into scanners on the first match, so changes made to them
+// after that are not seen.
 type Util struct{
 	MobileBrowsers []string
 	SmartTVBrowsers []string
 	DesktopBrowsers []string
 	MobileCatchAllIds map[string]string
-	isDesktopBrowser int
-	isMobileBrowser int
-	isSmartTV int 
+	AppKeywords []string
+	risMatcher matcher.Matcher
+	ldMatcher matcher.Matcher
+	// trace is set on the copies of a Util made by traced, and shared is
+	// the Util they were copied from, whose keyword scanners they use.
+	trace *matchTrace
+	shared *Util
+	scanners keywordScanners
 }
 
+// keywordScanners find any of the keywords of a list in a single pass
+// over a user agent, see matcher.AhoCorasick.
+type keywordScanners struct{
+	once sync.Once
+	mobile, desktop, smartTV, app *matcher.AhoCorasick
+}
+
+func (u *Util) keywords() *keywordScanners{
+	if u.shared != nil{
+		return u.shared.keywords()
+	}
+	u.scanners.once.Do(func(){
+		u.scanners.mobile = matcher.NewAhoCorasick(u.MobileBrowsers)
+		u.scanners.desktop = matcher.NewAhoCorasick(u.DesktopBrowsers)
+		u.scanners.smartTV = matcher.NewAhoCorasick(u.SmartTVBrowsers)
+		u.scanners.app = matcher.NewAhoCorasick(u.AppKeywords)
+	})
+	return &u.scanners
+}
+
+var (
+	localeRx = regexp.MustCompile(`; ?[a-z]{2}(?:-[a-zA-Z]{2})?(?:\.utf8|\.big5)?\b-?`)
+	desktopSafariRx = regexp.MustCompile(`^Mozilla/5\.0 \((?:Macintosh|Windows)[^\)]+\) AppleWebKit/[\d\.]+ \(KHTML, like Gecko\) Version/[\d\.]+ Safari/[\d\.]+$`)
+	ie9Rx = regexp.MustCompile(`^Mozilla\/5\.0 \(compatible; MSIE 9\.0; Windows NT \d\.\d`)
+	ieles9Rx = regexp.MustCompile(`^Mozilla\/4\.0 \(compatible; MSIE \d\.\d; Windows NT \d\.\d`)
+)
+
 func NewUtil() *Util{
 	mobileBrowsers := []string{
 		"midp",
@@ -83,6 +119,20 @@ func NewUtil() *Util{
         "aol 9.",
         "gtb8",
 	}
+	// Sent by native apps and in-app web views rather than by browsers.
+	appKeywords := []string{
+		"FBAN/",
+		"FBAV/",
+		"Instagram ",
+		"Twitter for ",
+		"Pinterest/",
+		"Snapchat/",
+		"Line/",
+		"; wv)",
+		"Dalvik/",
+		"okhttp/",
+		"CFNetwork/",
+	}
 	mobileCatchAllIds := map[string]string{
 		// Openwave.
         "UP.Browser/7.2": "opwv_v72_generic",
@@ -139,12 +189,33 @@ func NewUtil() *Util{
 		SmartTVBrowsers : smartTVBrowsers,
 		DesktopBrowsers : desktopBrowsers,
 		MobileCatchAllIds : mobileCatchAllIds,
+		AppKeywords : appKeywords,
+		risMatcher : new(matcher.RISMatcher),
+		ldMatcher : new(matcher.LDMatcher),
+	}
+}
+
+// traced returns a copy of u that records the matches made with it in t.
+func (u *Util) traced(t *matchTrace) *Util{
+	shared := u
+	if u.shared != nil{
+		shared = u.shared
+	}
+	return &Util{
+		MobileBrowsers : u.MobileBrowsers,
+		SmartTVBrowsers : u.SmartTVBrowsers,
+		DesktopBrowsers : u.DesktopBrowsers,
+		MobileCatchAllIds : u.MobileCatchAllIds,
+		AppKeywords : u.AppKeywords,
+		risMatcher : u.risMatcher,
+		ldMatcher : u.ldMatcher,
+		trace : t,
+		shared : shared,
 	}
 }
 
 func (u *Util) RemoveLocale(ua string) string{
-	wordRx := regexp.MustCompile(`; ?[a-z]{2}(?:-[a-zA-Z]{2})?(?:\.utf8|\.big5)?\b-?`)
-	return wordRx.ReplaceAllString(ua,`; xx-xx`)
+	return localeRx.ReplaceAllString(ua,`; xx-xx`)
 }
 
 func (u *Util) CheckIfContains(haystack, needle string) bool {
@@ -186,16 +257,16 @@ func (u *Util) CheckIfContainsCaseInsensitive(haystack, needle string) bool {
 	return u.CheckIfContains(strings.ToUpper(haystack),strings.ToUpper(needle))
 }
 
-var risMatcher = new(matcher.RISMatcher)
-
-var ldMatcher = new(matcher.LDMatcher)
-
 func (u *Util) RISMatch(collection []string, needle string, tolerance int) string{
-	return risMatcher.Match(collection,needle,tolerance)
+	match := u.risMatcher.Match(collection,needle,tolerance)
+	u.trace.lookup(MethodRIS,tolerance,match)
+	return match
 }
 
 func (u *Util) LDMatch(collection []string,needle string, tolerance int) string{
-	return ldMatcher.Match(collection,needle,tolerance)
+	match := u.ldMatcher.Match(collection,needle,tolerance)
+	u.trace.lookup(MethodLD,tolerance,match)
+	return match
 }
 
 func (u *Util) IndexOfOrLength(str string, target string, startIndex int) int{
@@ -224,64 +295,47 @@ func (u *Util) IndexOfAnyOrLength(ua string, needles []string,startIndex int) in
 
 }
 
+// Reset is kept for callers of the old API. Util no longer caches
+// anything between calls, so it is safe to share between goroutines and
+// there is nothing to reset.
 func (u *Util) Reset(){
-	u.isSmartTV = 0
-	u.isMobileBrowser = 0
-	u.isDesktopBrowser = 0
 }
 
 func (u *Util) IsMobileBrowser(ua string) bool{
-	if u.isMobileBrowser != 0{
-		return u.isMobileBrowser == 1
-	}
-	u.isMobileBrowser = -1
-	ua = strings.ToLower(ua)
-	for i := range u.MobileBrowsers{
-		if strings.Index(ua,u.MobileBrowsers[i]) != -1{
-			u.isMobileBrowser = 1
-			break
-		}
-	}
-	return u.isMobileBrowser == 1
+	return u.keywords().mobile.ContainsAny(strings.ToLower(ua))
 }
 
 func (u *Util) IsDesktopBrowser(ua string) bool{
-	if u.isDesktopBrowser != 0{
-		return u.isDesktopBrowser == 1
-	}
-	u.isDesktopBrowser = -1
-	ua = strings.ToLower(ua)
-	for i := range u.DesktopBrowsers{
-		if strings.Index(ua,u.DesktopBrowsers[i]) != -1{
-			u.isDesktopBrowser = 1
-			break
-		}
-	}
-	return u.isDesktopBrowser == 1
+	return u.keywords().desktop.ContainsAny(strings.ToLower(ua))
 }
 
 func (u *Util) IsSmartTV(ua string) bool{
-	if u.isSmartTV != 0{
-		return u.isSmartTV == 1
-	}
-	u.isSmartTV = -1
-	ua = strings.ToLower(ua)
-	for i := range u.SmartTVBrowsers{
-		if strings.Index(ua,u.SmartTVBrowsers[i]) != -1{
-			u.isSmartTV = 1
-			break
-		}
-	}
-	return u.isSmartTV == 1
+	return u.keywords().smartTV.ContainsAny(strings.ToLower(ua))
 }
 
+// IsApp reports whether ua contains one of the AppKeywords, which native
+// apps and in-app web views send.
+func (u *Util) IsApp(ua string) bool{
+	return u.keywords().app.ContainsAny(ua)
+}
+
+// GetMobileCatchAllId returns the id of the longest key of
+// MobileCatchAllIds that ua contains, so that UP.Browser/6.2 is preferred
+// to UP.Browser/6 whatever the order of the map.
 func (u *Util) GetMobileCatchAllId(ua string) string{
-	for key,deviceId := range u.MobileCatchAllIds{
+	best := ""
+	for key := range u.MobileCatchAllIds{
+		if len(key) < len(best) || (len(key) == len(best) && key > best){
+			continue
+		}
 		if strings.Index(ua,key) != -1{
-			return deviceId
+			best = key
 		}
 	}
-	return NO_MATCH
+	if best == ""{
+		return NO_MATCH
+	}
+	return u.MobileCatchAllIds[best]
 }
 
 func (u *Util) IsDesktopBrowserHeavyDutyAnalysis(ua string) bool{
@@ -300,9 +354,7 @@ func (u *Util) IsDesktopBrowserHeavyDutyAnalysis(ua string) bool{
 	if u.CheckIfContains(ua,"Firefox") && !u.CheckIfContains(ua,"Tablet"){
 		return true
 	}
-	safariRx := regexp.MustCompile(`^Mozilla/5\.0 \((?:Macintosh|Windows)[^\)]+\) AppleWebKit/[\d\.]+ \(KHTML, like Gecko\) Version/[\d\.]+ Safari/[\d\.]+$`)
-	matches := safariRx.FindStringSubmatch(ua)
-	if len(matches) != 0{
+	if desktopSafariRx.MatchString(ua){
 		return true
 	}
 	if u.CheckIfStartsWith(ua,"Opera/9.80 (Windows NT', 'Opera/9.80 (Macintosh"){
@@ -311,33 +363,24 @@ func (u *Util) IsDesktopBrowserHeavyDutyAnalysis(ua string) bool{
 	if u.IsDesktopBrowser(ua){
 		return true
 	}
-	ie9Rx := regexp.MustCompile(`^Mozilla\/5\.0 \(compatible; MSIE 9\.0; Windows NT \d\.\d`)
-	matches = ie9Rx.FindStringSubmatch(ua)
-	if len(matches) > 0{
-		return true
-	}
-	ieles9Rx := regexp.MustCompile(`^Mozilla\/4\.0 \(compatible; MSIE \d\.\d; Windows NT \d\.\d`)
-	matches = ieles9Rx.FindStringSubmatch(ua)
-	if len(matches) > 0{
+	if ie9Rx.MatchString(ua) || ieles9Rx.MatchString(ua){
 		return true
 	}
 	return false
 }
 
+// OrdinalIndexOf returns the index of the ordinal-th occurrence of
+// needle in haystack, counting from 1, or -1 if there are fewer.
 func (u *Util) OrdinalIndexOf(haystack,needle string,ordinal int) int{
-	var found = 0
-	var index = -1
-	for{
-		index = strings.Index(haystack[index:],needle)
-		if index < 0{
-			return index
-		}
-		found++
-		if found >= ordinal{
-			break
+	index := -1
+	for found := 0; found < ordinal; found++{
+		next := strings.Index(haystack[index + 1:],needle)
+		if next < 0{
+			return -1
 		}
+		index += 1 + next
 	}
-	return index	
+	return index
 }
 
 func (u *Util) FirstSlash(str string) int{
@@ -0,0 +1,194 @@
+package wurflgo
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Kinds of problems reported by Validate.
+const (
+	IssueDuplicateId      = "duplicate_id"
+	IssueMissingParent    = "missing_parent"
+	IssueFallBackCycle    = "fall_back_cycle"
+	IssueDuplicateUA      = "duplicate_user_agent"
+	IssueUnknownReference = "unknown_reference"
+)
+
+// FixedIdsHandler is implemented by handlers that may return device ids
+// of their own, such as the ids they pick in ApplyRecoveryMatch, rather
+// than only the ids of the user agents they were given. Validate reports
+// the ids that are not defined.
+type FixedIdsHandler interface {
+	FixedIds() []string
+}
+
+// ValidationIssue is one problem found in a set of device definitions.
+// For IssueUnknownReference DeviceId is the id that is referenced but not
+// defined.
+type ValidationIssue struct {
+	Kind     string
+	DeviceId string
+	Detail   string
+}
+
+func (i ValidationIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Kind, i.DeviceId, i.Detail)
+}
+
+// ValidationReport lists every problem Validate found, sorted by kind and
+// device id.
+type ValidationReport struct {
+	Devices int
+	Issues  []ValidationIssue
+}
+
+func (r *ValidationReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Count returns the number of issues of the given kind.
+func (r *ValidationReport) Count(kind string) int {
+	n := 0
+	for _, i := range r.Issues {
+		if i.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *ValidationReport) String() string {
+	lines := []string{fmt.Sprintf("%d devices, %d issues", r.Devices, len(r.Issues))}
+	for _, i := range r.Issues {
+		lines = append(lines, i.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks device definitions, as returned by ReadXML, before they
+// are registered: duplicate ids, fall_back devices that are not defined,
+// fall_back cycles, user agents shared by different devices and device
+// ids that the engine's handlers and Util may return but that are not
+// defined.
+func (e *Engine) Validate(devices []*DeviceDefinition) *ValidationReport {
+	report := &ValidationReport{Devices: len(devices)}
+	issue := func(kind, id, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, ValidationIssue{kind, id, fmt.Sprintf(format, args...)})
+	}
+
+	byId := make(map[string]*DeviceDefinition, len(devices))
+	uas := make(map[string]string, len(devices))
+	for _, dev := range devices {
+		if _, found := byId[dev.Id]; found {
+			issue(IssueDuplicateId, dev.Id, "defined more than once")
+			continue
+		}
+		byId[dev.Id] = dev
+		if dev.UserAgent == "" {
+			continue
+		}
+		if other, found := uas[dev.UserAgent]; found {
+			issue(IssueDuplicateUA, dev.Id, "user_agent is already used by %s", other)
+			continue
+		}
+		uas[dev.UserAgent] = dev.Id
+	}
+
+	cycles := NewStringSet()
+	for _, dev := range byId {
+		if dev.FallBack == "" {
+			continue
+		}
+		if byId[dev.FallBack] == nil {
+			issue(IssueMissingParent, dev.Id, "fall_back %s is not defined", dev.FallBack)
+			continue
+		}
+		// Report a cycle once, from the device of the cycle with the
+		// smallest id.
+		path := []string{dev.Id}
+		visited := NewStringSet()
+		visited.Add(dev.Id)
+		for cur := byId[dev.FallBack]; cur != nil; cur = byId[cur.FallBack] {
+			if cur.Id == dev.Id {
+				cycle := append([]string(nil), path...)
+				sort.Strings(cycle)
+				if cycles.Add(cycle[0]) {
+					issue(IssueFallBackCycle, cycle[0], "fall_back chain loops through %s", strings.Join(cycle, ", "))
+				}
+				break
+			}
+			if !visited.Add(cur.Id) {
+				break
+			}
+			path = append(path, cur.Id)
+		}
+	}
+
+	refs := make(map[string][]string)
+	for _, id := range []string{GENERIC, GENERIC_MOBILE, GENERIC_WEB_BROWSER, GENERIC_XHTML} {
+		refs[id] = append(refs[id], "wurflgo constants")
+	}
+	for _, id := range e.util.MobileCatchAllIds {
+		refs[id] = append(refs[id], "Util.MobileCatchAllIds")
+	}
+	for _, h := range e.Chain().Handlers {
+		fh, ok := h.(FixedIdsHandler)
+		if !ok {
+			continue
+		}
+		for _, id := range fh.FixedIds() {
+			refs[id] = append(refs[id], HandlerName(h)+".FixedIds")
+		}
+	}
+	for id, from := range refs {
+		if byId[id] == nil {
+			issue(IssueUnknownReference, id, "referenced by %s", strings.Join(uniqueSorted(from), ", "))
+		}
+	}
+
+	sort.Slice(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.DeviceId != b.DeviceId {
+			return a.DeviceId < b.DeviceId
+		}
+		return a.Detail < b.Detail
+	})
+	return report
+}
+
+// ValidateXML reads a wurfl.xml document and its patches and validates
+// the result. The error is only set when the documents cannot be read or
+// the patches conflict.
+func (e *Engine) ValidateXML(r io.Reader, patches ...io.Reader) (*ValidationReport, error) {
+	devices, err := ReadXML(r, patches...)
+	if err != nil {
+		return nil, err
+	}
+	return e.Validate(devices), nil
+}
+
+func Validate(devices []*DeviceDefinition) *ValidationReport {
+	return defaultEngine.Validate(devices)
+}
+
+func ValidateXML(r io.Reader, patches ...io.Reader) (*ValidationReport, error) {
+	return defaultEngine.ValidateXML(r, patches...)
+}
+
+func uniqueSorted(list []string) []string {
+	set := NewStringSet()
+	for _, s := range list {
+		set.Add(s)
+	}
+	unique := make([]string, 0, len(set.Set))
+	for s := range set.Set {
+		unique = append(unique, s)
+	}
+	sort.Strings(unique)
+	return unique
+}
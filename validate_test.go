@@ -0,0 +1,84 @@
+package wurflgo
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	devices := []*DeviceDefinition{
+		{Id: "generic"},
+		{Id: "test_a", UserAgent: "TestA/1.0", FallBack: "generic"},
+		{Id: "test_a", UserAgent: "TestA/2.0", FallBack: "generic"},
+		{Id: "test_b", UserAgent: "TestB/1.0", FallBack: "test_c"},
+		{Id: "test_c", UserAgent: "TestC/1.0", FallBack: "test_d"},
+		{Id: "test_d", UserAgent: "TestD/1.0", FallBack: "test_b"},
+		{Id: "test_e", UserAgent: "TestE/1.0", FallBack: "test_missing"},
+		{Id: "test_f", UserAgent: "TestA/1.0", FallBack: "test_a"},
+		// Falls back into the cycle without being part of it.
+		{Id: "test_g", UserAgent: "TestG/1.0", FallBack: "test_c"},
+	}
+	report := newTestEngine(t, nil).Validate(devices)
+	if report.Devices != len(devices) || report.OK() {
+		t.Fatalf("got %d devices, ok %v", report.Devices, report.OK())
+	}
+	issues := []ValidationIssue{}
+	for _, i := range report.Issues {
+		if i.Kind != IssueUnknownReference {
+			issues = append(issues, i)
+		}
+	}
+	want := []ValidationIssue{
+		{IssueDuplicateId, "test_a", "defined more than once"},
+		{IssueDuplicateUA, "test_f", "user_agent is already used by test_a"},
+		{IssueFallBackCycle, "test_b", "fall_back chain loops through test_b, test_c, test_d"},
+		{IssueMissingParent, "test_e", "fall_back test_missing is not defined"},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("got issues\n\t%v\nwant\n\t%v", issues, want)
+	}
+	if n := report.Count(IssueFallBackCycle); n != 1 {
+		t.Errorf("got %d cycles, want 1", n)
+	}
+}
+
+// TestValidateReferences checks the ids that handlers may return, through
+// FixedIdsHandler, and those of Util.MobileCatchAllIds.
+func TestValidateReferences(t *testing.T) {
+	e := newTestEngine(t, nil)
+	rule := testSamsungRule
+	rule.Recovery = []RecoveryRule{{"GT-I9300", "samsung_gt_i9300_ver1"}, {"GT-I9100", "samsung_gt_i9100_ver1"}}
+	rule.RecoveryDefault = "samsung_generic"
+	if err := e.SetHandlerRules([]HandlerRule{rule}); err != nil {
+		t.Fatal(err)
+	}
+	report, err := e.ValidateXML(bytes.NewReader(testXML(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := make(map[string]string)
+	for _, i := range report.Issues {
+		if i.Kind != IssueUnknownReference {
+			t.Errorf("testdata: %v", i)
+			continue
+		}
+		refs[i.DeviceId] = i.Detail
+	}
+	for id, detail := range map[string]string{
+		"generic_android_ver1_5":  "referenced by AndroidHandler.FixedIds",
+		"apple_ipod_touch_ver1":   "referenced by AppleHandler.FixedIds",
+		"blackberry_generic_ver2": "referenced by BlackBerryHandler.FixedIds",
+		"samsung_gt_i9100_ver1":   "referenced by SamsungRuleHandler.FixedIds",
+		"samsung_generic":         "referenced by SamsungRuleHandler.FixedIds",
+	} {
+		if refs[id] != detail {
+			t.Errorf("%s: got %q, want %q", id, refs[id], detail)
+		}
+	}
+	for _, id := range []string{GENERIC, GENERIC_MOBILE, GENERIC_XHTML, GENERIC_WEB_BROWSER, "samsung_gt_i9300_ver1", "apple_iphone_ver1", "apple_ipad_ver1"} {
+		if detail, found := refs[id]; found {
+			t.Errorf("%s is defined but reported: %s", id, detail)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package wurflgo
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Form factors returned by the form_factor virtual capability.
+const (
+	FormFactorDesktop        = "Desktop"
+	FormFactorTablet         = "Tablet"
+	FormFactorSmartphone     = "Smartphone"
+	FormFactorFeaturePhone   = "Feature Phone"
+	FormFactorSmartTV        = "Smart-TV"
+	FormFactorRobot          = "Robot"
+	FormFactorOtherMobile    = "Other Mobile"
+	FormFactorOtherNonMobile = "Other Non-Mobile"
+)
+
+// virtualRequest is what a virtual capability is computed from: the
+// matched device, the user agent it was matched from and the engine that
+// matched it.
+type virtualRequest struct {
+	e   *Engine
+	dev *Device
+	ua  string
+}
+
+var virtualCapabilities = map[string]func(r *virtualRequest) interface{}{
+	"is_mobile":                    (*virtualRequest).isMobile,
+	"is_phone":                     (*virtualRequest).isPhone,
+	"is_smartphone":                (*virtualRequest).isSmartphone,
+	"is_touchscreen":               (*virtualRequest).isTouchscreen,
+	"is_smarttv":                   (*virtualRequest).isSmartTV,
+	"is_full_desktop":              (*virtualRequest).isFullDesktop,
+	"is_robot":                     (*virtualRequest).isRobot,
+	"is_app":                       (*virtualRequest).isApp,
+	"is_android":                   func(r *virtualRequest) interface{} { return r.os() == "Android" },
+	"is_ios":                       func(r *virtualRequest) interface{} { return r.os() == "iOS" },
+	"advertised_browser":           func(r *virtualRequest) interface{} { return r.browser() },
+	"advertised_browser_version":   func(r *virtualRequest) interface{} { return r.browserVersion() },
+	"advertised_device_os":         func(r *virtualRequest) interface{} { return r.os() },
+	"advertised_device_os_version": func(r *virtualRequest) interface{} { return r.osVersion() },
+	"form_factor":                  func(r *virtualRequest) interface{} { return r.formFactor() },
+}
+
+// VirtualCapabilityNames returns the names of the virtual capabilities,
+// sorted.
+func VirtualCapabilityNames() []string {
+	names := make([]string, 0, len(virtualCapabilities))
+	for name := range virtualCapabilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// VirtualCapability computes a virtual capability, such as is_smartphone
+// or form_factor, for dev as matched from ua. Virtual capabilities are
+// derived from the capabilities of the device and from the user agent
+// itself, which tells apart browsers and OS versions that share a device.
+func (e *Engine) VirtualCapability(dev *Device, ua, name string) (interface{}, error) {
+	fn, found := virtualCapabilities[name]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCapability, name)
+	}
+	return fn(&virtualRequest{e, dev, ua}), nil
+}
+
+// VirtualCapabilities computes every virtual capability for dev as matched
+// from ua.
+func (e *Engine) VirtualCapabilities(dev *Device, ua string) map[string]interface{} {
+	r := &virtualRequest{e, dev, ua}
+	values := make(map[string]interface{}, len(virtualCapabilities))
+	for name, fn := range virtualCapabilities {
+		values[name] = fn(r)
+	}
+	return values
+}
+
+func VirtualCapability(dev *Device, ua, name string) (interface{}, error) {
+	return defaultEngine.VirtualCapability(dev, ua, name)
+}
+
+func VirtualCapabilities(dev *Device, ua string) map[string]interface{} {
+	return defaultEngine.VirtualCapabilities(dev, ua)
+}
+
+func (r *virtualRequest) boolCap(name string) (bool, bool) {
+	if r.dev == nil {
+		return false, false
+	}
+	b, err := r.dev.Bool(name)
+	return b, err == nil
+}
+
+func (r *virtualRequest) stringCap(name string) string {
+	if r.dev == nil {
+		return ""
+	}
+	s, _ := r.dev.String(name)
+	return s
+}
+
+func (r *virtualRequest) intCap(name string) int {
+	if r.dev == nil {
+		return 0
+	}
+	i, _ := r.dev.Int(name)
+	return i
+}
+
+func (r *virtualRequest) isMobile() interface{} {
+	if wireless, found := r.boolCap("is_wireless_device"); found {
+		return wireless
+	}
+	return r.e.util.IsMobileBrowser(r.ua)
+}
+
+func (r *virtualRequest) isSmartTV() interface{} {
+	if tv, found := r.boolCap("is_smarttv"); found {
+		return tv
+	}
+	return r.e.util.IsSmartTV(r.ua)
+}
+
+func (r *virtualRequest) isTouchscreen() interface{} {
+	return r.stringCap("pointing_method") == "touchscreen"
+}
+
+func (r *virtualRequest) isPhone() interface{} {
+	phone, _ := r.boolCap("can_assign_phone_number")
+	return phone && r.isMobile() == true
+}
+
+func (r *virtualRequest) isSmartphone() interface{} {
+	if r.isPhone() != true || r.isTouchscreen() != true {
+		return false
+	}
+	if tablet, _ := r.boolCap("is_tablet"); tablet {
+		return false
+	}
+	return r.intCap("resolution_width") >= 320
+}
+
+func (r *virtualRequest) isFullDesktop() interface{} {
+	if desktop, found := r.boolCap("ux_full_desktop"); found {
+		return desktop
+	}
+	return r.e.util.IsDesktopBrowser(r.ua)
+}
+
+// isRobot asks the handler that files crawlers and transcoders, so that a
+// user agent it would claim is a robot even when it matched another
+// device.
+func (r *virtualRequest) isRobot() interface{} {
+	if bot, _ := r.boolCap("is_bot"); bot {
+		return true
+	}
+	if h := r.e.Chain().Handler("BotCrawlerTranscoderHandler"); h != nil {
+		return h.CanHandle(r.ua)
+	}
+	return false
+}
+
+// isApp reports user agents sent by native apps and their web views
+// rather than by a browser.
+func (r *virtualRequest) isApp() interface{} {
+	if r.e.util.IsApp(r.ua) {
+		return true
+	}
+	for _, h := range r.e.Chain().Handlers {
+		if app, ok := h.(*AppHandler); ok && app.CanHandle(r.ua) {
+			return true
+		}
+	}
+	apple := r.apple()
+	return apple != nil && apple.CanHandle(r.ua) && apple.IsWebView(r.ua)
+}
+
+func (r *virtualRequest) formFactor() string {
+	switch {
+	case r.isRobot() == true:
+		return FormFactorRobot
+	case r.isMobile() != true:
+		switch {
+		case r.isSmartTV() == true:
+			return FormFactorSmartTV
+		case r.isFullDesktop() == true:
+			return FormFactorDesktop
+		}
+		return FormFactorOtherNonMobile
+	}
+	if tablet, _ := r.boolCap("is_tablet"); tablet {
+		return FormFactorTablet
+	}
+	switch {
+	case r.isSmartphone() == true:
+		return FormFactorSmartphone
+	case r.isPhone() == true:
+		return FormFactorFeaturePhone
+	}
+	return FormFactorOtherMobile
+}
+
+type advertisedRule struct {
+	name string
+	rx   *regexp.Regexp
+}
+
+// The first rule whose expression matches the user agent names the
+// browser or OS; its first non-empty group, if any, is the version.
+var (
+	advertisedBrowsers = []advertisedRule{
+		{"Opera Mini", regexp.MustCompile(`Opera Mini/([\d.]+)`)},
+		{"Opera", regexp.MustCompile(`(?:OPR|Opera)/(?:.*Version/)?([\d.]+)`)},
+		{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/([\d.]+)`)},
+		{"Samsung Browser", regexp.MustCompile(`SamsungBrowser/([\d.]+)`)},
+		{"UC Browser", regexp.MustCompile(`UC ?Browser/([\d.]+)`)},
+		{"IEMobile", regexp.MustCompile(`IEMobile[/ ]([\d.]+)`)},
+		{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`)},
+		{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`)},
+		{"IE", regexp.MustCompile(`MSIE ([\d.]+)|Trident/.*rv:([\d.]+)`)},
+		{"Android Webkit", regexp.MustCompile(`Android.*Version/([\d.]+)`)},
+		{"Mobile Safari", regexp.MustCompile(`Version/([\d.]+).*Mobile.*Safari/`)},
+		{"Safari", regexp.MustCompile(`Version/([\d.]+).*Safari/`)},
+	}
+	advertisedOSes = []advertisedRule{
+		{"Windows Phone", regexp.MustCompile(`Windows Phone(?: OS)? ([\d.]+)`)},
+		{"Android", regexp.MustCompile(`Android[ /]?([\d.]*)`)},
+		{"iOS", regexp.MustCompile(`(?:iPhone|iPad|iPod).*? OS ([\d_]+)`)},
+		{"BlackBerry OS", regexp.MustCompile(`BlackBerry.*?Version/([\d.]+)`)},
+		{"Symbian", regexp.MustCompile(`Symbian(?:OS)?/([\d.]+)`)},
+		{"Mac OS X", regexp.MustCompile(`Mac OS X ([\d_.]+)`)},
+		{"Windows", regexp.MustCompile(`Windows NT ([\d.]+)`)},
+		{"Linux", regexp.MustCompile(`Linux`)},
+	}
+)
+
+func advertised(ua string, rules []advertisedRule) (string, string) {
+	for _, rule := range rules {
+		m := rule.rx.FindStringSubmatch(ua)
+		if m == nil {
+			continue
+		}
+		for _, version := range m[1:] {
+			if version != "" {
+				return rule.name, strings.Replace(version, "_", ".", -1)
+			}
+		}
+		return rule.name, ""
+	}
+	return "", ""
+}
+
+// browser and os fall back to the capabilities of the device when the
+// user agent does not name a known browser or OS.
+func (r *virtualRequest) browser() string {
+	if name, _ := advertised(r.ua, advertisedBrowsers); name != "" {
+		return name
+	}
+	return r.stringCap("mobile_browser")
+}
+
+func (r *virtualRequest) browserVersion() string {
+	if name, version := advertised(r.ua, advertisedBrowsers); name != "" {
+		return version
+	}
+	return r.stringCap("mobile_browser_version")
+}
+
+// apple returns the engine's AppleHandler, if its chain has one.
+func (r *virtualRequest) apple() *AppleHandler {
+	apple, _ := r.e.Chain().Handler("AppleHandler").(*AppleHandler)
+	return apple
+}
+
+// iPadDesktopMode reports iPads asking for desktop sites, whose user
+// agent advertises macOS.
+func (r *virtualRequest) iPadDesktopMode() bool {
+	apple := r.apple()
+	return apple != nil && apple.IsIPadDesktopMode(r.ua)
+}
+
+func (r *virtualRequest) os() string {
+	if r.iPadDesktopMode() {
+		return "iOS"
+	}
+	if name, _ := advertised(r.ua, advertisedOSes); name != "" {
+		return name
+	}
+	return r.stringCap("device_os")
+}
+
+func (r *virtualRequest) osVersion() string {
+	if r.iPadDesktopMode() {
+		if major, minor := r.apple().IOSVersion(r.ua); major >= 0 {
+			return strconv.Itoa(major) + "." + strconv.Itoa(minor)
+		}
+		return ""
+	}
+	if name, version := advertised(r.ua, advertisedOSes); name != "" {
+		return version
+	}
+	return r.stringCap("device_os_version")
+}
@@ -0,0 +1,39 @@
+package wurflgo
+
+import "testing"
+
+func TestIsApp(t *testing.T) {
+	e := newTestEngine(t, nil)
+	if err := e.CustomizeChain(func(c *Chain) error {
+		return c.InsertBefore("AndroidHandler", NewAppHandler("AcmeAppHandler", []string{"AcmeApp/"}, CreateGenericNormalizers()))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		ua   string
+		want bool
+	}{
+		// Feature phone browsers are mobile but not apps.
+		{"Nokia6300/2.0 (04.20) Profile/MIDP-2.0 Configuration/CLDC-1.1", false},
+		{"SonyEricssonK800i/R1KG Browser/NetFront/3.3 Profile/MIDP-2.0 Configuration/CLDC-1.1", false},
+		{"DoCoMo/2.0 N905i(c100;TB;W24H16)", false},
+		{"SAGEM-myX5-2/1.0 Profile/MIDP-2.0 Configuration/CLDC-1.0", false},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1", false},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", false},
+		// App keywords.
+		{"Mozilla/5.0 (Linux; Android 13; SM-S911B Build/TP1A.220624.014; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/119.0.6045.163 Mobile Safari/537.36", true},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/21B80 [FBAN/FBIOS;FBAV/442.0.0.38.113]", true},
+		// AppHandler.
+		{"AcmeApp/5.3 (iPhone14,2; iOS 17.1; Scale/3.00)", true},
+		// Apple web view without an app keyword.
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/21B80", true},
+	} {
+		got, err := e.VirtualCapability(e.Match(test.ua), test.ua, "is_app")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("is_app(%q) = %v, want %v", test.ua, got, test.want)
+		}
+	}
+}